@@ -58,9 +58,97 @@ func runHealthChecker(cliContext *cli.Context) error {
 	if len(opts.Ports) > 0 {
 		opts.Logger.Infof("The Health Check will attempt to connect to the following ports via TCP: %v", opts.Ports)
 	}
+	for _, tcp := range opts.TcpChecks {
+		if tcp.Negate {
+			opts.Logger.Infof("The Health Check will check that port %d is not open", tcp.Port)
+			continue
+		}
+		opts.Logger.Infof("The Health Check will attempt to connect to port %d via TCP and exchange a payload", tcp.Port)
+	}
 	if len(opts.Scripts) > 0 {
 		opts.Logger.Infof("The Health Check will attempt to run the following scripts: %v", opts.Scripts)
 	}
+	for _, dns := range opts.DnsChecks {
+		opts.Logger.Infof("The Health Check will attempt to resolve the %s record for %s", dns.RecordType, dns.Name)
+	}
+	for _, udp := range opts.UdpChecks {
+		opts.Logger.Infof("The Health Check will attempt to send a UDP probe to %s", udp.Address)
+	}
+	for _, zookeeper := range opts.ZookeeperChecks {
+		opts.Logger.Infof("The Health Check will attempt to send four letter words to ZooKeeper at %s", zookeeper.Address)
+	}
+	for _, tls := range opts.TlsChecks {
+		opts.Logger.Infof("The Health Check will attempt a TLS handshake with %s", tls.Address)
+	}
+	for _, disk := range opts.DiskChecks {
+		opts.Logger.Infof("The Health Check will check the disk usage of %s", disk.Path)
+	}
+	for range opts.LoadChecks {
+		opts.Logger.Infof("The Health Check will check the load average in %s", opts.ProcRoot)
+	}
+	for range opts.MemoryChecks {
+		opts.Logger.Infof("The Health Check will check memory usage in %s", opts.ProcRoot)
+	}
+	for _, pressure := range opts.PressureChecks {
+		opts.Logger.Infof("The Health Check will check %s pressure in %s", pressure.Resource, opts.ProcRoot)
+	}
+	for _, process := range opts.ProcessChecks {
+		opts.Logger.Infof("The Health Check will check that processes %s are running", process.Matcher)
+	}
+	for _, socketOwner := range opts.SocketOwnerChecks {
+		opts.Logger.Infof("The Health Check will check that port %d is owned by a process %s", socketOwner.Port, socketOwner.Matcher)
+	}
+	for _, quorum := range opts.QuorumChecks {
+		opts.Logger.Infof("The Health Check will check that at least %d of %d checks pass in the group %s", quorum.Required(), quorum.Total(), quorum.Name)
+	}
+	for _, file := range opts.FileChecks {
+		opts.Logger.Infof("The Health Check will inspect the file %s", file.Path)
+	}
+	for _, logPattern := range opts.LogPatternChecks {
+		opts.Logger.Infof("The Health Check will tail %s for lines matching %q", logPattern.Path, logPattern.Match)
+	}
+	for _, http := range opts.HttpChecks {
+		opts.Logger.Infof("The Health Check will attempt to send a %s request to %s", http.Method, http.Url)
+	}
+	for _, scenario := range opts.ScenarioChecks {
+		opts.Logger.Infof("The Health Check will run the %d steps of scenario %s", len(scenario.Steps), scenario.Name)
+	}
+	for _, unix := range opts.UnixChecks {
+		opts.Logger.Infof("The Health Check will attempt to connect to the Unix socket %s", unix.Path)
+	}
+	for _, docker := range opts.DockerChecks {
+		opts.Logger.Infof("The Health Check will check that containers %s are healthy", docker)
+	}
+	for _, redis := range opts.RedisChecks {
+		opts.Logger.Infof("The Health Check will attempt to send PING to Redis at %s", redis.Address)
+	}
+	for _, postgres := range opts.PostgresChecks {
+		opts.Logger.Infof("The Health Check will attempt the PostgreSQL startup handshake with %s", postgres.Address)
+	}
+	for _, mysql := range opts.MysqlChecks {
+		opts.Logger.Infof("The Health Check will attempt the MySQL handshake with %s", mysql.Address)
+	}
+	for _, memcached := range opts.MemcachedChecks {
+		opts.Logger.Infof("The Health Check will attempt to read stats from memcached at %s", memcached.Address)
+	}
+	for _, stats := range opts.StatsChecks {
+		opts.Logger.Infof("The Health Check will attempt to read stats from %s", stats.Address)
+	}
+	for _, prometheus := range opts.PrometheusChecks {
+		opts.Logger.Infof("The Health Check will attempt to scrape metrics from %s", prometheus.Url)
+	}
+	for _, ping := range opts.PingChecks {
+		opts.Logger.Infof("The Health Check will attempt to ping %s", ping.Host)
+	}
+	for _, websocket := range opts.WebsocketChecks {
+		opts.Logger.Infof("The Health Check will attempt a WebSocket handshake with %s", websocket.Url)
+	}
+	for _, starlark := range opts.StarlarkChecks {
+		opts.Logger.Infof("The Health Check will attempt to run the Starlark script %s", starlark.Path)
+	}
+	if opts.HaproxyAgentListener != "" {
+		opts.Logger.Infof("HAProxy agent checks will be answered on %s", opts.HaproxyAgentListener)
+	}
 	opts.Logger.Infof("Listening on Port %s...", opts.Listener)
 	err = server.StartHttpServer(opts)
 	if err != nil {
@@ -14,16 +14,157 @@ import (
 const DEFAULT_LISTENER_IP_ADDRESS = "0.0.0.0"
 const DEFAULT_LISTENER_PORT = 5500
 const DEFAULT_SCRIPT_TIMEOUT_SEC = 5
+const DEFAULT_PROC_ROOT = "/proc"
 const ENV_VAR_NAME_DEBUG_MODE = "HEALTH_CHECKER_DEBUG"
 
-var portFlag = cli.IntSliceFlag{
+var portFlag = cli.StringSliceFlag{
 	Name:  "port",
-	Usage: fmt.Sprintf("[One of port/script Required] The port number on which a TCP connection will be attempted. Specify one or more times. Example: 8000"),
+	Usage: fmt.Sprintf("[At least one check Required] The port number, or an inclusive range of up to 256 port numbers, on which a TCP connection will be attempted. Specify one or more times. Example: 8000 or 8000-8007"),
+}
+
+var tcpFlag = cli.StringSliceFlag{
+	Name:  "tcp",
+	Usage: fmt.Sprintf("[At least one check Required] A TCP connection that optionally sends a payload and expects a response, as a spec with the keys port, send (or send-hex), expect and expect-regex. Specify one or more times. Example: \"port=6379,send=PING\\r\\n,expect=+PONG\""),
 }
 
 var scriptFlag = cli.StringSliceFlag{
 	Name:  "script",
-	Usage: fmt.Sprintf("[One of port/script Required] The path to script that will be run. Specify one or more times. Example: \"/usr/local/bin/health-check.sh --http-port 8000\""),
+	Usage: fmt.Sprintf("[At least one check Required] The path to script that will be run. Specify one or more times. Example: \"/usr/local/bin/health-check.sh --http-port 8000\""),
+}
+
+var negatedScriptFlag = cli.StringSliceFlag{
+	Name:  "negated-script",
+	Usage: fmt.Sprintf("[At least one check Required] The path to a script that is expected to fail. The check passes only if the script exits with a non-zero status. Specify one or more times. Example: \"/usr/local/bin/is-maintenance-mode.sh\""),
+}
+
+var dnsFlag = cli.StringSliceFlag{
+	Name:  "dns",
+	Usage: fmt.Sprintf("[At least one check Required] A name that will be resolved via DNS, as a spec with the keys name, server, type (one of %s), expect and max-latency. Specify one or more times. Example: \"name=consul.service.consul,server=127.0.0.1:8600,type=A,max-latency=100ms\"", strings.Join(options.DnsRecordTypes, ", ")),
+}
+
+var udpFlag = cli.StringSliceFlag{
+	Name:  "udp",
+	Usage: fmt.Sprintf("[At least one check Required] A UDP probe, as a spec with the keys address, send (or send-hex), expect and expect-regex. Specify one or more times. Example: \"address=127.0.0.1:8125,send=health:1|c\""),
+}
+
+var zookeeperFlag = cli.StringSliceFlag{
+	Name:  "zookeeper",
+	Usage: fmt.Sprintf("[At least one check Required] A ZooKeeper server to check with four letter words, as a spec with the keys address, state and min-synced-followers. Specify one or more times. Example: \"address=127.0.0.1:2181,state=leader,state=follower\""),
+}
+
+var tlsFlag = cli.StringSliceFlag{
+	Name:  "tls",
+	Usage: fmt.Sprintf("[At least one check Required] A TLS handshake, as a spec with the keys address, server-name, ca-file, warn-days and fail-days. Specify one or more times. Example: \"address=127.0.0.1:443,server-name=example.com,warn-days=30,fail-days=7\""),
+}
+
+var diskFlag = cli.StringSliceFlag{
+	Name:  "disk",
+	Usage: fmt.Sprintf("[At least one check Required] A filesystem to check for free space and inodes, as a spec with the keys path, warn-used-percent, fail-used-percent, warn-free-bytes, fail-free-bytes, warn-free-inodes and fail-free-inodes. Specify one or more times. Example: \"path=/,warn-used-percent=80,fail-used-percent=95\""),
+}
+
+var loadFlag = cli.StringSliceFlag{
+	Name:  "load",
+	Usage: fmt.Sprintf("[At least one check Required] A check on the load average, as a spec with the keys warn-load1, fail-load1, warn-load5, fail-load5, warn-load15, fail-load15 and per-cpu. Specify one or more times. Example: \"fail-load5=2,per-cpu=true\""),
+}
+
+var memoryFlag = cli.StringSliceFlag{
+	Name:  "memory",
+	Usage: fmt.Sprintf("[At least one check Required] A check on memory and swap usage, as a spec with the keys warn-available-percent, fail-available-percent, warn-swap-used-percent and fail-swap-used-percent. Specify one or more times. Example: \"fail-available-percent=5\""),
+}
+
+var pressureFlag = cli.StringSliceFlag{
+	Name:  "pressure",
+	Usage: fmt.Sprintf("[At least one check Required] A check on pressure stall information, as a spec with the keys resource, kind, window, warn-stalled-percent and fail-stalled-percent. Specify one or more times. Example: \"resource=memory,kind=full,window=avg60,fail-stalled-percent=20\""),
+}
+
+var processFlag = cli.StringSliceFlag{
+	Name:  "process",
+	Usage: fmt.Sprintf("[At least one check Required] A check that processes are running, as a spec with the keys pidfile, name, cmdline, min, max, no-zombies and min-uptime. Specify one or more times. Example: \"cmdline=kafka\\.Kafka,max=1,no-zombies=true,min-uptime=30s\""),
+}
+
+var socketOwnerFlag = cli.StringSliceFlag{
+	Name:  "socket-owner",
+	Usage: fmt.Sprintf("[At least one check Required] A check that the TCP socket listening on a port is owned by the expected process, as a spec with the keys port, pidfile, name and cmdline. Specify one or more times. Example: \"port=8080,pidfile=/var/run/app.pid\""),
+}
+
+var quorumFlag = cli.StringSliceFlag{
+	Name:  "quorum",
+	Usage: fmt.Sprintf("[At least one check Required] A group of port and script checks that passes when at least a minimum number of them pass, as a spec with the keys name, port, script and min. Specify one or more times. Example: \"port=8000-8007,min=6\""),
+}
+
+var fileFlag = cli.StringSliceFlag{
+	Name:  "file",
+	Usage: fmt.Sprintf("[At least one check Required] A file that must exist and optionally be fresh and have the expected size and contents, as a spec with the keys path, max-age, min-size, max-size, match, json-field and json-value. Specify one or more times. Example: \"path=/var/run/agent.heartbeat,max-age=5m\""),
+}
+
+var logPatternFlag = cli.StringSliceFlag{
+	Name:  "log-pattern",
+	Usage: fmt.Sprintf("[At least one check Required] A log file to tail for lines matching a pattern, as a spec with the keys path, match, window and max. Specify one or more times. Example: \"path=/var/log/app.log,match=OutOfMemoryError|FATAL,window=5m,max=0\""),
+}
+
+var httpFlag = cli.StringSliceFlag{
+	Name:  "http",
+	Usage: fmt.Sprintf("[At least one check Required] An HTTP request, as a spec with the keys url, method, status, timeout, expect-json and warn-json. Specify one or more times. Example: \"url=http://127.0.0.1:9200/_cluster/health,expect-json=status in green|yellow,warn-json=status == green\""),
+}
+
+var scenarioFlag = cli.StringSliceFlag{
+	Name:  "scenario",
+	Usage: fmt.Sprintf("[At least one check Required] A series of HTTP requests defined in a JSON file, as a spec with the keys file and timeout. Specify one or more times. Example: \"file=/etc/health-checker/login.json,timeout=10s\""),
+}
+
+var unixFlag = cli.StringSliceFlag{
+	Name:  "unix",
+	Usage: fmt.Sprintf("[At least one check Required] A Unix domain socket to connect to, as a spec with the key path. A path starting with @ is a socket in the abstract namespace. Specify one or more times. Example: \"path=/var/run/docker.sock\""),
+}
+
+var dockerFlag = cli.StringSliceFlag{
+	Name:  "docker",
+	Usage: fmt.Sprintf("[At least one check Required] A check that Docker containers are running and healthy, as a spec with the keys name, label, min and socket. Specify one or more times. Example: \"label=com.example.service=api,min=2\""),
+}
+
+var redisFlag = cli.StringSliceFlag{
+	Name:  "redis",
+	Usage: fmt.Sprintf("[At least one check Required] A Redis server to check with PING and INFO, as a spec with the keys address, username, password-file, role and expect-info. Specify one or more times. Example: \"address=127.0.0.1:6379,role=replica,expect-info=master_link_status:up,expect-info=loading:0\""),
+}
+
+var postgresFlag = cli.StringSliceFlag{
+	Name:  "postgres",
+	Usage: fmt.Sprintf("[At least one check Required] A PostgreSQL server to check with the startup handshake, and optionally by logging in, as a spec with the keys address, user, password-file, database, sslmode and role. Specify one or more times. Example: \"address=127.0.0.1:5432,user=health,password-file=/etc/pg-password,role=primary\""),
+}
+
+var mysqlFlag = cli.StringSliceFlag{
+	Name:  "mysql",
+	Usage: fmt.Sprintf("[At least one check Required] A MySQL server to check with the initial handshake, and optionally by logging in, as a spec with the keys address, user, password-file, database and role. Specify one or more times. Example: \"address=127.0.0.1:3306,user=health,password-file=/etc/mysql-password,role=replica\""),
+}
+
+var memcachedFlag = cli.StringSliceFlag{
+	Name:  "memcached",
+	Usage: fmt.Sprintf("[At least one check Required] A memcached server to send stats to, and assertions about the reply, as a spec with the keys address, expect-stat and warn-stat. Specify one or more times. Example: \"expect-stat=curr_connections < ${max_connections},warn-stat=evictions_per_second < 100\""),
+}
+
+var statsFlag = cli.StringSliceFlag{
+	Name:  "stats",
+	Usage: fmt.Sprintf("[At least one check Required] A command to send to a daemon that replies with lines of statistics, and assertions about them, as a spec with the keys address, send, send-hex, prefix, separator, end, expect-stat and warn-stat. Specify one or more times. Example: \"address=127.0.0.1:9999,send=show info\\n,separator=:,expect-stat=CurrConns < ${Maxconn}\""),
+}
+
+var prometheusFlag = cli.StringSliceFlag{
+	Name:  "prometheus",
+	Usage: fmt.Sprintf("[At least one check Required] A Prometheus metrics endpoint to scrape, and assertions about its series, as a spec with the keys url, unix-socket, timeout, expect-metric and warn-metric. Specify one or more times. Example: \"url=http://127.0.0.1:9100/metrics,expect-metric=queue_depth{queue=\"emails\"} < 1000\""),
+}
+
+var pingFlag = cli.StringSliceFlag{
+	Name:  "ping",
+	Usage: fmt.Sprintf("[At least one check Required] A host to send ICMP echo requests to, with limits on the packet loss and round trip time, as a spec with the keys host, ip-version, count, interval, timeout, warn-loss-percent, fail-loss-percent, warn-rtt-ms and fail-rtt-ms. Specify one or more times. Example: \"host=10.0.0.1,count=5,fail-loss-percent=50,warn-rtt-ms=20\""),
+}
+
+var websocketFlag = cli.StringSliceFlag{
+	Name:  "websocket",
+	Usage: fmt.Sprintf("[At least one check Required] A WebSocket handshake, with an optional message exchange, as a spec with the keys url, origin, send, send-hex, expect, expect-regex and timeout. Specify one or more times. Example: \"url=ws://127.0.0.1:8080/socket,send=ping,expect=pong\""),
+}
+
+var starlarkFlag = cli.StringSliceFlag{
+	Name:  "starlark",
+	Usage: fmt.Sprintf("[At least one check Required] A Starlark script to run in-process, which passes if its check() function succeeds, as a spec with the keys file, name, max-steps and timeout. Specify one or more times. Example: \"file=/etc/health-checker/replication.star,timeout=2s\""),
 }
 
 var scriptTimeoutFlag = cli.IntFlag{
@@ -32,6 +173,12 @@ var scriptTimeoutFlag = cli.IntFlag{
 	Value: DEFAULT_SCRIPT_TIMEOUT_SEC,
 }
 
+var procRootFlag = cli.StringFlag{
+	Name:  "proc-root",
+	Usage: fmt.Sprintf("[Optional] The directory where the proc filesystem is mounted, which is read by the load, memory, pressure, process and socket-owner checks."),
+	Value: DEFAULT_PROC_ROOT,
+}
+
 var singleflightFlag = cli.BoolFlag{
 	Name:  "singleflight",
 	Usage: fmt.Sprintf("[Optional] Enable singleflight mode, which makes concurrent requests share the same check."),
@@ -43,18 +190,87 @@ var listenerFlag = cli.StringFlag{
 	Value: fmt.Sprintf("%s:%d", DEFAULT_LISTENER_IP_ADDRESS, DEFAULT_LISTENER_PORT),
 }
 
+var haproxyAgentListenerFlag = cli.StringFlag{
+	Name:  "haproxy-agent-listener",
+	Usage: fmt.Sprintf("[Optional] The IP address and port on which inbound HAProxy agent-check connections will be accepted. Example: 0.0.0.0:5501"),
+}
+
 var logLevelFlag = cli.StringFlag{
 	Name:  "log-level",
 	Usage: fmt.Sprintf("[Optional] Set the log level to `LEVEL`. Must be one of: %v", logrus.AllLevels),
 	Value: logrus.InfoLevel.String(),
 }
 
+// The flags that each configure one or more checks. At least one check is required.
+var checkFlags = []cli.Flag{
+	portFlag,
+	tcpFlag,
+	scriptFlag,
+	negatedScriptFlag,
+	dnsFlag,
+	udpFlag,
+	zookeeperFlag,
+	tlsFlag,
+	diskFlag,
+	loadFlag,
+	memoryFlag,
+	pressureFlag,
+	processFlag,
+	socketOwnerFlag,
+	quorumFlag,
+	fileFlag,
+	logPatternFlag,
+	httpFlag,
+	scenarioFlag,
+	unixFlag,
+	dockerFlag,
+	redisFlag,
+	postgresFlag,
+	mysqlFlag,
+	memcachedFlag,
+	statsFlag,
+	prometheusFlag,
+	pingFlag,
+	websocketFlag,
+	starlarkFlag,
+}
+
 var defaultFlags = []cli.Flag{
 	portFlag,
+	tcpFlag,
 	scriptFlag,
+	negatedScriptFlag,
+	dnsFlag,
+	udpFlag,
+	zookeeperFlag,
+	tlsFlag,
+	diskFlag,
+	loadFlag,
+	memoryFlag,
+	pressureFlag,
+	processFlag,
+	socketOwnerFlag,
+	quorumFlag,
+	fileFlag,
+	logPatternFlag,
+	httpFlag,
+	scenarioFlag,
+	unixFlag,
+	dockerFlag,
+	redisFlag,
+	postgresFlag,
+	mysqlFlag,
+	memcachedFlag,
+	statsFlag,
+	prometheusFlag,
+	pingFlag,
+	websocketFlag,
+	starlarkFlag,
 	scriptTimeoutFlag,
+	procRootFlag,
 	singleflightFlag,
 	listenerFlag,
+	haproxyAgentListenerFlag,
 	logLevelFlag,
 }
 
@@ -78,17 +294,154 @@ func parseOptions(cliContext *cli.Context) (*options.Options, error) {
 	}
 	logger.SetLevel(level)
 
-	ports := cliContext.IntSlice("port")
+	ports, err := options.ParsePorts(cliContext.StringSlice("port"))
+	if err != nil {
+		return nil, InvalidParam{portFlag.Name, err}
+	}
+
+	tcpChecks, err := options.ParseTcpChecks(cliContext.StringSlice("tcp"))
+	if err != nil {
+		return nil, InvalidParam{tcpFlag.Name, err}
+	}
 
 	scriptArr := cliContext.StringSlice("script")
 	scripts := options.ParseScripts(scriptArr)
+	scripts = append(scripts, options.ParseNegatedScripts(cliContext.StringSlice("negated-script"))...)
 
-	if len(ports) == 0 && len(scripts) == 0 {
-		return nil, OneOfParamsRequired{portFlag.Name, scriptFlag.Name}
+	dnsChecks, err := options.ParseDnsChecks(cliContext.StringSlice("dns"))
+	if err != nil {
+		return nil, InvalidParam{dnsFlag.Name, err}
+	}
+
+	udpChecks, err := options.ParseUdpChecks(cliContext.StringSlice("udp"))
+	if err != nil {
+		return nil, InvalidParam{udpFlag.Name, err}
+	}
+
+	zookeeperChecks, err := options.ParseZookeeperChecks(cliContext.StringSlice("zookeeper"))
+	if err != nil {
+		return nil, InvalidParam{zookeeperFlag.Name, err}
+	}
+
+	tlsChecks, err := options.ParseTlsChecks(cliContext.StringSlice("tls"))
+	if err != nil {
+		return nil, InvalidParam{tlsFlag.Name, err}
+	}
+
+	diskChecks, err := options.ParseDiskChecks(cliContext.StringSlice("disk"))
+	if err != nil {
+		return nil, InvalidParam{diskFlag.Name, err}
+	}
+
+	loadChecks, err := options.ParseLoadChecks(cliContext.StringSlice("load"))
+	if err != nil {
+		return nil, InvalidParam{loadFlag.Name, err}
+	}
+
+	memoryChecks, err := options.ParseMemoryChecks(cliContext.StringSlice("memory"))
+	if err != nil {
+		return nil, InvalidParam{memoryFlag.Name, err}
+	}
+
+	pressureChecks, err := options.ParsePressureChecks(cliContext.StringSlice("pressure"))
+	if err != nil {
+		return nil, InvalidParam{pressureFlag.Name, err}
+	}
+
+	processChecks, err := options.ParseProcessChecks(cliContext.StringSlice("process"))
+	if err != nil {
+		return nil, InvalidParam{processFlag.Name, err}
+	}
+
+	socketOwnerChecks, err := options.ParseSocketOwnerChecks(cliContext.StringSlice("socket-owner"))
+	if err != nil {
+		return nil, InvalidParam{socketOwnerFlag.Name, err}
+	}
+
+	quorumChecks, err := options.ParseQuorumChecks(cliContext.StringSlice("quorum"))
+	if err != nil {
+		return nil, InvalidParam{quorumFlag.Name, err}
+	}
+
+	fileChecks, err := options.ParseFileChecks(cliContext.StringSlice("file"))
+	if err != nil {
+		return nil, InvalidParam{fileFlag.Name, err}
+	}
+
+	logPatternChecks, err := options.ParseLogPatternChecks(cliContext.StringSlice("log-pattern"))
+	if err != nil {
+		return nil, InvalidParam{logPatternFlag.Name, err}
+	}
+
+	httpChecks, err := options.ParseHttpChecks(cliContext.StringSlice("http"))
+	if err != nil {
+		return nil, InvalidParam{httpFlag.Name, err}
+	}
+
+	scenarioChecks, err := options.ParseScenarioChecks(cliContext.StringSlice("scenario"))
+	if err != nil {
+		return nil, InvalidParam{scenarioFlag.Name, err}
+	}
+
+	unixChecks, err := options.ParseUnixChecks(cliContext.StringSlice("unix"))
+	if err != nil {
+		return nil, InvalidParam{unixFlag.Name, err}
+	}
+
+	dockerChecks, err := options.ParseDockerChecks(cliContext.StringSlice("docker"))
+	if err != nil {
+		return nil, InvalidParam{dockerFlag.Name, err}
+	}
+
+	redisChecks, err := options.ParseRedisChecks(cliContext.StringSlice("redis"))
+	if err != nil {
+		return nil, InvalidParam{redisFlag.Name, err}
+	}
+
+	postgresChecks, err := options.ParsePostgresChecks(cliContext.StringSlice("postgres"))
+	if err != nil {
+		return nil, InvalidParam{postgresFlag.Name, err}
+	}
+
+	mysqlChecks, err := options.ParseMysqlChecks(cliContext.StringSlice("mysql"))
+	if err != nil {
+		return nil, InvalidParam{mysqlFlag.Name, err}
+	}
+
+	memcachedChecks, err := options.ParseMemcachedChecks(cliContext.StringSlice("memcached"))
+	if err != nil {
+		return nil, InvalidParam{memcachedFlag.Name, err}
+	}
+
+	statsChecks, err := options.ParseStatsChecks(cliContext.StringSlice("stats"))
+	if err != nil {
+		return nil, InvalidParam{statsFlag.Name, err}
+	}
+
+	prometheusChecks, err := options.ParsePrometheusChecks(cliContext.StringSlice("prometheus"))
+	if err != nil {
+		return nil, InvalidParam{prometheusFlag.Name, err}
+	}
+
+	pingChecks, err := options.ParsePingChecks(cliContext.StringSlice("ping"))
+	if err != nil {
+		return nil, InvalidParam{pingFlag.Name, err}
+	}
+
+	websocketChecks, err := options.ParseWebsocketChecks(cliContext.StringSlice("websocket"))
+	if err != nil {
+		return nil, InvalidParam{websocketFlag.Name, err}
+	}
+
+	starlarkChecks, err := options.ParseStarlarkChecks(cliContext.StringSlice("starlark"))
+	if err != nil {
+		return nil, InvalidParam{starlarkFlag.Name, err}
 	}
 
 	singleflight := cliContext.Bool("singleflight")
 
+	procRoot := cliContext.String("proc-root")
+
 	scriptTimeout := cliContext.Int("script-timeout")
 
 	listener := cliContext.String("listener")
@@ -96,14 +449,59 @@ func parseOptions(cliContext *cli.Context) (*options.Options, error) {
 		return nil, MissingParam(listenerFlag.Name)
 	}
 
-	return &options.Options{
-		Ports:         ports,
-		Scripts:       scripts,
-		ScriptTimeout: scriptTimeout,
-		Singleflight:  singleflight,
-		Listener:      listener,
-		Logger:        logger,
-	}, nil
+	haproxyAgentListener := cliContext.String("haproxy-agent-listener")
+
+	opts := &options.Options{
+		Ports:                ports,
+		TcpChecks:            tcpChecks,
+		Scripts:              scripts,
+		DnsChecks:            dnsChecks,
+		UdpChecks:            udpChecks,
+		ZookeeperChecks:      zookeeperChecks,
+		TlsChecks:            tlsChecks,
+		DiskChecks:           diskChecks,
+		LoadChecks:           loadChecks,
+		MemoryChecks:         memoryChecks,
+		PressureChecks:       pressureChecks,
+		ProcessChecks:        processChecks,
+		SocketOwnerChecks:    socketOwnerChecks,
+		QuorumChecks:         quorumChecks,
+		FileChecks:           fileChecks,
+		LogPatternChecks:     logPatternChecks,
+		HttpChecks:           httpChecks,
+		ScenarioChecks:       scenarioChecks,
+		UnixChecks:           unixChecks,
+		DockerChecks:         dockerChecks,
+		RedisChecks:          redisChecks,
+		PostgresChecks:       postgresChecks,
+		MysqlChecks:          mysqlChecks,
+		MemcachedChecks:      memcachedChecks,
+		StatsChecks:          statsChecks,
+		PrometheusChecks:     prometheusChecks,
+		PingChecks:           pingChecks,
+		WebsocketChecks:      websocketChecks,
+		StarlarkChecks:       starlarkChecks,
+		ScriptTimeout:        scriptTimeout,
+		ProcRoot:             procRoot,
+		Singleflight:         singleflight,
+		Listener:             listener,
+		HaproxyAgentListener: haproxyAgentListener,
+		Logger:               logger,
+	}
+
+	if opts.NumChecks() == 0 {
+		return nil, OneOfParamsRequired(checkFlagNames())
+	}
+
+	return opts, nil
+}
+
+func checkFlagNames() []string {
+	names := []string{}
+	for _, flag := range checkFlags {
+		names = append(names, flag.GetName())
+	}
+	return names
 }
 
 // Some error types are simple enough that we'd rather just show the error message directly instead of vomiting out a
@@ -129,11 +527,17 @@ func (paramName MissingParam) Error() string {
 	return fmt.Sprintf("Missing required parameter --%s", string(paramName))
 }
 
-type OneOfParamsRequired struct {
-	param1 string
-	param2 string
+type InvalidParam struct {
+	param string
+	err   error
+}
+
+func (invalidParam InvalidParam) Error() string {
+	return fmt.Sprintf("Invalid value for parameter --%s: %s", invalidParam.param, invalidParam.err)
 }
 
+type OneOfParamsRequired []string
+
 func (paramNames OneOfParamsRequired) Error() string {
-	return fmt.Sprintf("Missing required parameter, one of --%s / --%s required", string(paramNames.param1), string(paramNames.param2))
+	return fmt.Sprintf("Missing required parameter, one of --%s required", strings.Join(paramNames, " / --"))
 }
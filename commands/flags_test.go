@@ -2,12 +2,17 @@ package commands
 
 import (
 	"flag"
+	"fmt"
 	"github.com/gruntwork-io/health-checker/options"
 	"github.com/gruntwork-io/health-checker/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/urfave/cli"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseChecksFromConfig(t *testing.T) {
@@ -55,6 +60,24 @@ func TestParseChecksFromConfig(t *testing.T) {
 			createOptionsForTest(t, DEFAULT_SCRIPT_TIMEOUT_SEC, []string{}, defaultListener(), []int{8080, 8081}),
 			"",
 		},
+		{
+			"port range",
+			[]string{"--port", "8000-8002", "--port", "9000"},
+			createOptionsForTest(t, DEFAULT_SCRIPT_TIMEOUT_SEC, []string{}, defaultListener(), []int{8000, 8001, 8002, 9000}),
+			"",
+		},
+		{
+			"invalid port range",
+			[]string{"--port", "8007-8000"},
+			nil,
+			"\"8007-8000\" is not a port or a range of ports",
+		},
+		{
+			"port range too large",
+			[]string{"--port", "1-65535"},
+			nil,
+			"\"1-65535\" covers more than 256 ports",
+		},
 		{
 			"both port and script",
 			[]string{"--port", "8080", "--script", "\"/usr/local/bin/check.sh 1234\""},
@@ -102,6 +125,694 @@ func TestParseChecksFromConfig(t *testing.T) {
 
 }
 
+func TestParseDnsChecks(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name              string
+		args              []string
+		expectedDnsChecks []options.DnsCheck
+		expectedErr       string
+	}{
+		{
+			"name only",
+			[]string{"--dns", "name=example.com"},
+			[]options.DnsCheck{{Name: "example.com", RecordType: "A", ExpectedAnswers: nil}},
+			"",
+		},
+		{
+			"all keys",
+			[]string{"--dns", "name=example.com,server=10.0.0.2,type=aaaa,expect=::1,expect=::2,max-latency=150ms"},
+			[]options.DnsCheck{{Name: "example.com", Server: "10.0.0.2:53", RecordType: "AAAA", ExpectedAnswers: []string{"::1", "::2"}, MaxLatency: 150 * time.Millisecond}},
+			"",
+		},
+		{
+			"multiple checks",
+			[]string{"--dns", "name=a.example.com,server=127.0.0.1:8600", "--dns", "name=b.example.com,type=TXT"},
+			[]options.DnsCheck{{Name: "a.example.com", Server: "127.0.0.1:8600", RecordType: "A"}, {Name: "b.example.com", RecordType: "TXT"}},
+			"",
+		},
+		{
+			"missing name",
+			[]string{"--dns", "server=127.0.0.1"},
+			nil,
+			"missing required key \"name\"",
+		},
+		{
+			"unknown key",
+			[]string{"--dns", "name=example.com,port=53"},
+			nil,
+			"unknown key \"port\"",
+		},
+		{
+			"unsupported type",
+			[]string{"--dns", "name=example.com,type=SOA"},
+			nil,
+			"unsupported record type \"SOA\"",
+		},
+		{
+			"invalid max latency",
+			[]string{"--dns", "name=example.com,max-latency=fast"},
+			nil,
+			"the value of \"max-latency\" must be a duration",
+		},
+	}
+
+	for _, testCase := range testCases {
+		context := createContextForTesting(testCase.args)
+		actualOptions, actualErr := parseOptions(context)
+
+		if testCase.expectedErr != "" {
+			if assert.NotNil(t, actualErr, testCase.name) {
+				assert.Contains(t, actualErr.Error(), testCase.expectedErr, testCase.name)
+			}
+		} else if assert.Nil(t, actualErr, testCase.name) {
+			assert.Equal(t, testCase.expectedDnsChecks, actualOptions.DnsChecks, testCase.name)
+		}
+	}
+}
+
+func TestParseUdpChecks(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		args            []string
+		expectedPayload []byte
+		expectedExpect  string
+		expectedPattern string
+		expectedErr     string
+	}{
+		{"text payload", []string{"--udp", "address=127.0.0.1:514,send=<14>health\\n"}, []byte("<14>health\n"), "", "", ""},
+		{"hex payload", []string{"--udp", "address=127.0.0.1:53,send-hex=00 01 ff,expect-regex=^ok"}, []byte{0, 1, 255}, "", "^ok", ""},
+		{"expect is not a pattern", []string{"--udp", "address=127.0.0.1:53,send=ping,expect=("}, []byte("ping"), "(", "", ""},
+		{"escaped comma", []string{"--udp", "address=127.0.0.1:53,send=a\\,b"}, []byte("a,b"), "", "", ""},
+		{"missing address", []string{"--udp", "send=ping"}, nil, "", "", "missing required key \"address\""},
+		{"both payloads", []string{"--udp", "address=127.0.0.1:53,send=a,send-hex=00"}, nil, "", "", "only one of \"send\" and \"send-hex\""},
+		{"invalid hex", []string{"--udp", "address=127.0.0.1:53,send-hex=zz"}, nil, "", "", "must be a hex string"},
+		{"invalid pattern", []string{"--udp", "address=127.0.0.1:53,expect-regex=("}, nil, "", "", "not a valid regular expression"},
+	}
+
+	for _, testCase := range testCases {
+		context := createContextForTesting(testCase.args)
+		actualOptions, actualErr := parseOptions(context)
+
+		if testCase.expectedErr != "" {
+			if assert.NotNil(t, actualErr, testCase.name) {
+				assert.Contains(t, actualErr.Error(), testCase.expectedErr, testCase.name)
+			}
+		} else if assert.Nil(t, actualErr, testCase.name) && assert.Len(t, actualOptions.UdpChecks, 1, testCase.name) {
+			udp := actualOptions.UdpChecks[0]
+			assert.Equal(t, testCase.expectedPayload, udp.Payload, testCase.name)
+			assert.Equal(t, testCase.expectedExpect, udp.Expect, testCase.name)
+			if testCase.expectedPattern != "" {
+				assert.Equal(t, testCase.expectedPattern, udp.ExpectRegex.String(), testCase.name)
+			}
+		}
+	}
+}
+
+func TestParseTcpChecks(t *testing.T) {
+	t.Parallel()
+
+	context := createContextForTesting([]string{"--tcp", "port=6379,send=PING\\r\\n,expect=+PONG", "--tcp", "port=22,expect-regex=^SSH-2\\.0-"})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) && assert.Len(t, actualOptions.TcpChecks, 2) {
+		assert.Equal(t, 6379, actualOptions.TcpChecks[0].Port)
+		assert.Equal(t, []byte("PING\r\n"), actualOptions.TcpChecks[0].Payload)
+		assert.Equal(t, "+PONG", actualOptions.TcpChecks[0].Expect)
+		assert.Equal(t, 22, actualOptions.TcpChecks[1].Port)
+		assert.Equal(t, "^SSH-2\\.0-", actualOptions.TcpChecks[1].ExpectRegex.String())
+	}
+
+	_, actualErr = parseOptions(createContextForTesting([]string{"--tcp", "send=PING"}))
+	if assert.NotNil(t, actualErr) {
+		assert.Contains(t, actualErr.Error(), "missing required key \"port\"")
+	}
+
+	for _, port := range []string{"0", "65536", "-1"} {
+		_, actualErr = parseOptions(createContextForTesting([]string{"--tcp", "port=" + port}))
+		if assert.NotNil(t, actualErr, port) {
+			assert.Contains(t, actualErr.Error(), "must be a port number between 1 and 65535", port)
+		}
+	}
+}
+
+func TestParseNegatedChecks(t *testing.T) {
+	t.Parallel()
+
+	context := createContextForTesting([]string{"--tcp", "port=9010,negate=true", "--script", "/bin/true", "--negated-script", "/bin/false -v"})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) && assert.Len(t, actualOptions.TcpChecks, 1) {
+		assert.True(t, actualOptions.TcpChecks[0].Negate)
+		assert.Equal(t, []options.Script{
+			{Name: "/bin/true", Args: []string{}},
+			{Name: "/bin/false", Args: []string{"-v"}, Negate: true},
+		}, actualOptions.Scripts)
+	}
+
+	_, actualErr = parseOptions(createContextForTesting([]string{"--tcp", "port=9010,negate=maybe"}))
+	if assert.NotNil(t, actualErr) {
+		assert.Contains(t, actualErr.Error(), "the value of \"negate\" must be true or false")
+	}
+}
+
+func TestParseQuorumChecks(t *testing.T) {
+	t.Parallel()
+
+	context := createContextForTesting([]string{"--quorum", "name=workers,port=8000-8007,min=6", "--quorum", "port=9000,port=9001,script=/bin/check.sh --fast,min=50%"})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) && assert.Len(t, actualOptions.QuorumChecks, 2) {
+		workers := actualOptions.QuorumChecks[0]
+		assert.Equal(t, "workers", workers.Name)
+		assert.Equal(t, []int{8000, 8001, 8002, 8003, 8004, 8005, 8006, 8007}, workers.Ports)
+		assert.Equal(t, 6, workers.Required())
+
+		mixed := actualOptions.QuorumChecks[1]
+		assert.Equal(t, "of 3 checks", mixed.Name)
+		assert.Equal(t, []options.Script{{Name: "/bin/check.sh", Args: []string{"--fast"}}}, mixed.Scripts)
+		assert.Equal(t, 2, mixed.Required())
+	}
+
+	testCases := []struct {
+		name        string
+		spec        string
+		expectedErr string
+	}{
+		{"no children", "min=1", "at least one port or script is required"},
+		{"min too large", "port=8000-8001,min=3", "must be a number of checks between 1 and 2"},
+		{"invalid percentage", "port=8000-8001,min=150%", "must be a number of checks or a percentage"},
+		{"port range too large", "port=8000-9000", "\"8000-9000\" covers more than 256 ports"},
+	}
+
+	for _, testCase := range testCases {
+		_, actualErr := parseOptions(createContextForTesting([]string{"--quorum", testCase.spec}))
+		if assert.NotNil(t, actualErr, testCase.name) {
+			assert.Contains(t, actualErr.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestParseFileChecks(t *testing.T) {
+	t.Parallel()
+
+	context := createContextForTesting([]string{"--file", "path=/var/run/agent.json,max-age=5m,min-size=1,max-size=1M,json-field=status.state,json-value=ok"})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) {
+		assert.Equal(t, []options.FileCheck{{
+			Path:      "/var/run/agent.json",
+			MaxAge:    5 * time.Minute,
+			MinSize:   1,
+			MaxSize:   1 << 20,
+			JsonField: "status.state",
+			JsonValue: "ok",
+		}}, actualOptions.FileChecks)
+	}
+
+	_, actualErr = parseOptions(createContextForTesting([]string{"--file", "path=/var/run/agent.json,json-field=status.state"}))
+	if assert.NotNil(t, actualErr) {
+		assert.Contains(t, actualErr.Error(), "must be set together")
+	}
+}
+
+func TestParseHttpChecks(t *testing.T) {
+	t.Parallel()
+
+	context := createContextForTesting([]string{"--http", "url=http://127.0.0.1:9200/_cluster/health,status=200,expect-json=status in green|yellow,warn-json=status == green,warn-json=active_shards >= 10"})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) && assert.Len(t, actualOptions.HttpChecks, 1) {
+		check := actualOptions.HttpChecks[0]
+		assert.Equal(t, "GET", check.Method)
+		assert.Equal(t, []int{200}, check.ExpectStatus)
+		assert.Equal(t, []options.JsonAssertion{
+			{Path: "status", Operator: "in", Values: []string{"green", "yellow"}},
+			{Path: "status", Operator: "==", Values: []string{"green"}, Warn: true},
+			{Path: "active_shards", Operator: ">=", Values: []string{"10"}, Warn: true},
+		}, check.JsonAssertions)
+	}
+
+	context = createContextForTesting([]string{"--http", "url=http://localhost/_ping,unix-socket=/var/run/docker.sock", "--unix", "path=@/containerd-shim/k8s.sock"})
+	actualOptions, actualErr = parseOptions(context)
+	if assert.Nil(t, actualErr) && assert.Len(t, actualOptions.HttpChecks, 1) && assert.Len(t, actualOptions.UnixChecks, 1) {
+		assert.Equal(t, "/var/run/docker.sock", actualOptions.HttpChecks[0].UnixSocket)
+		assert.Equal(t, "@/containerd-shim/k8s.sock", actualOptions.UnixChecks[0].Path)
+	}
+
+	testCases := []struct {
+		name        string
+		spec        string
+		expectedErr string
+	}{
+		{"missing url", "method=GET", "missing required key \"url\""},
+		{"not http", "url=ftp://example.com", "must be an http:// or https:// URL"},
+		{"invalid status", "url=http://example.com,status=999", "must be an HTTP status code"},
+		{"unknown operator", "url=http://example.com,expect-json=status ~ green", "unknown operator \"~\""},
+		{"missing value", "url=http://example.com,expect-json=status ==", "== requires a value"},
+		{"not a number", "url=http://example.com,expect-json=active_shards > many", "> requires a number"},
+	}
+
+	for _, testCase := range testCases {
+		_, actualErr := parseOptions(createContextForTesting([]string{"--http", testCase.spec}))
+		if assert.NotNil(t, actualErr, testCase.name) {
+			assert.Contains(t, actualErr.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestParseScenarioChecks(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "health-checker-scenario-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name        string
+		contents    string
+		expectedErr string
+	}{
+		{"valid", `{"name": "login", "steps": [{"url": "http://127.0.0.1/login", "method": "post", "extract": [{"var": "token", "json": "token"}]}, {"url": "http://127.0.0.1/items?token=${token}", "status": [200, 204]}]}`, ""},
+		{"no steps", `{"steps": []}`, "at least one step is required"},
+		{"no url", `{"steps": [{"name": "login"}]}`, "step 1 (login) has no url"},
+		{"undefined variable", `{"steps": [{"url": "http://127.0.0.1/items?token=${token}"}]}`, "step 1 references the variable token before it is extracted"},
+		{"invalid regex", `{"steps": [{"url": "http://127.0.0.1/", "extract": [{"var": "id", "regex": "("}]}]}`, "step 1 has an invalid regex for id"},
+		{"invalid assertion", `{"steps": [{"url": "http://127.0.0.1/", "expect-json": ["status ~ ok"]}]}`, "unknown operator"},
+		{"invalid json", `{"steps": `, "unexpected end of JSON input"},
+	}
+
+	for i, testCase := range testCases {
+		path := filepath.Join(dir, fmt.Sprintf("scenario-%d.json", i))
+		if err := ioutil.WriteFile(path, []byte(testCase.contents), 0644); err != nil {
+			assert.FailNow(t, "Failed to write scenario: %v", err.Error())
+		}
+
+		actualOptions, actualErr := parseOptions(createContextForTesting([]string{"--scenario", "file=" + path + ",timeout=3s"}))
+		if testCase.expectedErr != "" {
+			if assert.NotNil(t, actualErr, testCase.name) {
+				assert.Contains(t, actualErr.Error(), testCase.expectedErr, testCase.name)
+			}
+		} else if assert.Nil(t, actualErr, testCase.name) && assert.Len(t, actualOptions.ScenarioChecks, 1, testCase.name) {
+			scenario := actualOptions.ScenarioChecks[0]
+			assert.Equal(t, "login", scenario.Name)
+			assert.Equal(t, 3*time.Second, scenario.Timeout)
+			assert.Equal(t, "POST", scenario.Steps[0].Method)
+			assert.Equal(t, "GET", scenario.Steps[1].Method)
+			assert.Equal(t, []int{200, 204}, scenario.Steps[1].ExpectStatus)
+		}
+	}
+}
+
+func TestParseDockerChecks(t *testing.T) {
+	t.Parallel()
+
+	context := createContextForTesting([]string{"--docker", "name=/web", "--docker", "label=com.example.service=api,label=canary,min=2,socket=/run/docker.sock"})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) {
+		assert.Equal(t, []options.DockerCheck{
+			{Socket: "/var/run/docker.sock", Name: "web", Labels: nil, Min: 1},
+			{Socket: "/run/docker.sock", Labels: []string{"com.example.service=api", "canary"}, Min: 2},
+		}, actualOptions.DockerChecks)
+	}
+
+	_, actualErr = parseOptions(createContextForTesting([]string{"--docker", "min=2"}))
+	if assert.NotNil(t, actualErr) {
+		assert.Contains(t, actualErr.Error(), "at least one of \"name\" and \"label\" is required")
+	}
+
+	_, actualErr = parseOptions(createContextForTesting([]string{"--docker", "name=web,min=0"}))
+	if assert.NotNil(t, actualErr) {
+		assert.Contains(t, actualErr.Error(), "the value of \"min\" must be a positive integer")
+	}
+}
+
+func TestParseRedisChecks(t *testing.T) {
+	t.Parallel()
+
+	passwordFile, err := ioutil.TempFile("", "health-checker-redis-password")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp file: %v", err.Error())
+	}
+	defer os.Remove(passwordFile.Name())
+	passwordFile.WriteString("s3cr3t\n")
+	passwordFile.Close()
+
+	context := createContextForTesting([]string{"--redis", "password-file=" + passwordFile.Name() + ",role=replica,expect-info=master_link_status:up,expect-info=loading:0"})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) {
+		assert.Equal(t, []options.RedisCheck{{
+			Address:    "127.0.0.1:6379",
+			Password:   "s3cr3t",
+			Roles:      []string{"slave"},
+			ExpectInfo: map[string]string{"master_link_status": "up", "loading": "0"},
+		}}, actualOptions.RedisChecks)
+	}
+
+	testCases := []struct {
+		name        string
+		spec        string
+		expectedErr string
+	}{
+		{"unknown role", "role=leader", "unknown role \"leader\""},
+		{"invalid expect-info", "expect-info=loading", "expected field:value"},
+		{"password", "password=s3cr3t", "\"password\" would show up in the process list, use \"password-file\" instead"},
+		{"missing password-file", "password-file=/does/not/exist", "no such file or directory"},
+	}
+
+	for _, testCase := range testCases {
+		_, actualErr := parseOptions(createContextForTesting([]string{"--redis", testCase.spec}))
+		if assert.NotNil(t, actualErr, testCase.name) {
+			assert.Contains(t, actualErr.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestParseDatabaseChecks(t *testing.T) {
+	t.Parallel()
+
+	passwordFile, err := ioutil.TempFile("", "health-checker-database-password")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp file: %v", err.Error())
+	}
+	defer os.Remove(passwordFile.Name())
+	passwordFile.WriteString("s3cr3t\n")
+	passwordFile.Close()
+
+	context := createContextForTesting([]string{
+		"--postgres", "address=db:5432",
+		"--postgres", "user=health,password-file=" + passwordFile.Name() + ",role=PRIMARY,sslmode=require",
+		"--mysql", "user=health,password-file=" + passwordFile.Name() + ",database=app,role=replica",
+	})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) {
+		assert.Equal(t, []options.PostgresCheck{
+			{Address: "db:5432", Database: "postgres", SslMode: "disable", Roles: []string{}},
+			{Address: "127.0.0.1:5432", User: "health", Password: "s3cr3t", Database: "postgres", SslMode: "require", Roles: []string{"primary"}},
+		}, actualOptions.PostgresChecks)
+		assert.Equal(t, []options.MysqlCheck{
+			{Address: "127.0.0.1:3306", User: "health", Password: "s3cr3t", Database: "app", Roles: []string{"replica"}},
+		}, actualOptions.MysqlChecks)
+	}
+
+	testCases := []struct {
+		name        string
+		flag        string
+		spec        string
+		expectedErr string
+	}{
+		{"unknown role", "--postgres", "user=health,role=standby", "unknown role \"standby\""},
+		{"role without user", "--mysql", "role=primary", "\"role\" and \"password-file\" require \"user\""},
+		{"password-file without user", "--postgres", "password-file=" + passwordFile.Name(), "\"role\" and \"password-file\" require \"user\""},
+		{"inline postgres password", "--postgres", "user=health,password=s3cr3t", "use \"password-file\" instead"},
+		{"inline mysql password", "--mysql", "user=health,password=s3cr3t", "use \"password-file\" instead"},
+		{"missing password-file", "--mysql", "user=health,password-file=/does/not/exist", "no such file or directory"},
+	}
+
+	for _, testCase := range testCases {
+		_, actualErr := parseOptions(createContextForTesting([]string{testCase.flag, testCase.spec}))
+		if assert.NotNil(t, actualErr, testCase.name) {
+			assert.Contains(t, actualErr.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestParseStatsChecks(t *testing.T) {
+	t.Parallel()
+
+	context := createContextForTesting([]string{
+		"--memcached", "expect-stat=curr_connections < ${max_connections},warn-stat=evictions_per_second < 100",
+		"--stats", "address=127.0.0.1:9999,send=show info\\n,separator=:,expect-stat=CurrConns < ${Maxconn}",
+	})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) {
+		assert.Equal(t, []options.StatsCheck{{
+			Protocol: "memcached",
+			Address:  "127.0.0.1:11211",
+			Payload:  []byte("stats\r\n"),
+			Prefix:   "STAT ",
+			End:      "END",
+			Assertions: []options.JsonAssertion{
+				{Path: "curr_connections", Operator: "<", Values: []string{"${max_connections}"}},
+				{Path: "evictions_per_second", Operator: "<", Values: []string{"100"}, Warn: true},
+			},
+		}}, actualOptions.MemcachedChecks)
+		assert.Equal(t, []options.StatsCheck{{
+			Protocol:   "text",
+			Address:    "127.0.0.1:9999",
+			Payload:    []byte("show info\n"),
+			Separator:  ":",
+			Assertions: []options.JsonAssertion{{Path: "CurrConns", Operator: "<", Values: []string{"${Maxconn}"}}},
+		}}, actualOptions.StatsChecks)
+	}
+
+	testCases := []struct {
+		name        string
+		flag        string
+		spec        string
+		expectedErr string
+	}{
+		{"missing address", "--stats", "send=stats,expect-stat=uptime > 0", "missing required key \"address\""},
+		{"missing assertions", "--stats", "address=127.0.0.1:9999,send=show info", "at least one of \"expect-stat\" and \"warn-stat\" is required"},
+		{"invalid reference", "--memcached", "expect-stat=curr_connections < max_connections", "< requires a number or a ${path} reference"},
+	}
+
+	for _, testCase := range testCases {
+		_, actualErr := parseOptions(createContextForTesting([]string{testCase.flag, testCase.spec}))
+		if assert.NotNil(t, actualErr, testCase.name) {
+			assert.Contains(t, actualErr.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestParsePrometheusChecks(t *testing.T) {
+	t.Parallel()
+
+	context := createContextForTesting([]string{"--prometheus", `url=http://127.0.0.1:9100/metrics,expect-metric=queue_depth{queue="emails"\,region="us-east-1"} < 1000,expect-metric=process_open_fds<${process_max_fds{job="api"}},warn-metric=up == 1`})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) {
+		assert.Equal(t, []options.PrometheusCheck{{
+			Url:     "http://127.0.0.1:9100/metrics",
+			Timeout: 5 * time.Second,
+			Assertions: []options.MetricAssertion{
+				{Name: "queue_depth", Labels: map[string]string{"queue": "emails", "region": "us-east-1"}, Operator: "<", Value: 1000},
+				{
+					Name:      "process_open_fds",
+					Labels:    map[string]string{},
+					Operator:  "<",
+					Reference: &options.MetricSeries{Name: "process_max_fds", Labels: map[string]string{"job": "api"}},
+				},
+				{Name: "up", Labels: map[string]string{}, Operator: "==", Value: 1, Warn: true},
+			},
+		}}, actualOptions.PrometheusChecks)
+	}
+
+	testCases := []struct {
+		name        string
+		spec        string
+		expectedErr string
+	}{
+		{"missing url", "expect-metric=up == 1", "missing required key \"url\""},
+		{"missing assertions", "url=http://127.0.0.1:9100/metrics", "at least one of \"expect-metric\" and \"warn-metric\" is required"},
+		{"missing operator", "url=http://127.0.0.1:9100/metrics,expect-metric=up", "expected <series> <operator> <value>"},
+		{"unknown operator", "url=http://127.0.0.1:9100/metrics,expect-metric=up ~ 1", "unknown operator \"~\", must be one of: ==, !=, <=, >=, <, >"},
+		{"not a number", "url=http://127.0.0.1:9100/metrics,expect-metric=up == yes", "== requires a number or a ${series} reference"},
+		{"invalid reference", "url=http://127.0.0.1:9100/metrics,expect-metric=up == ${1up}", "== requires a number or a ${series} reference"},
+		{"unterminated label", `url=http://127.0.0.1:9100/metrics,expect-metric=up{job="api} == 1`, "the value of label job is not terminated"},
+	}
+
+	for _, testCase := range testCases {
+		_, actualErr := parseOptions(createContextForTesting([]string{"--prometheus", testCase.spec}))
+		if assert.NotNil(t, actualErr, testCase.name) {
+			assert.Contains(t, actualErr.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestParsePingChecks(t *testing.T) {
+	t.Parallel()
+
+	context := createContextForTesting([]string{
+		"--ping", "host=10.0.0.1",
+		"--ping", "host=gateway.internal,ip-version=6,count=5,interval=1s,timeout=500ms,warn-loss-percent=10,fail-loss-percent=50,fail-rtt-ms=20",
+	})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) {
+		assert.Equal(t, []options.PingCheck{
+			{Host: "10.0.0.1", Count: 3, Interval: 200 * time.Millisecond, Timeout: time.Second},
+			{
+				Host:        "gateway.internal",
+				IpVersion:   6,
+				Count:       5,
+				Interval:    time.Second,
+				Timeout:     500 * time.Millisecond,
+				LossPercent: options.Thresholds{Warn: 10, Fail: 50},
+				RttMs:       options.Thresholds{Fail: 20},
+			},
+		}, actualOptions.PingChecks)
+	}
+
+	testCases := []struct {
+		name        string
+		spec        string
+		expectedErr string
+	}{
+		{"missing host", "count=3", "missing required key \"host\""},
+		{"invalid ip-version", "host=10.0.0.1,ip-version=5", "the value of \"ip-version\" must be 4 or 6"},
+		{"invalid count", "host=10.0.0.1,count=0", "the value of \"count\" must be a positive integer"},
+		{"invalid threshold", "host=10.0.0.1,fail-rtt-ms=fast", "the value of \"fail-rtt-ms\" must be a number"},
+	}
+
+	for _, testCase := range testCases {
+		_, actualErr := parseOptions(createContextForTesting([]string{"--ping", testCase.spec}))
+		if assert.NotNil(t, actualErr, testCase.name) {
+			assert.Contains(t, actualErr.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestParseWebsocketChecks(t *testing.T) {
+	t.Parallel()
+
+	context := createContextForTesting([]string{
+		"--websocket", "url=wss://gateway.example.com/socket?v=2,origin=https://example.com,send=ping,expect=pong,timeout=2s",
+		"--websocket", "url=ws://127.0.0.1:8080/socket,send-hex=00ff",
+	})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) {
+		assert.Equal(t, []options.WebsocketCheck{{
+			Url:     "wss://gateway.example.com/socket?v=2",
+			Origin:  "https://example.com",
+			Payload: []byte("ping"),
+			Expect:  "pong",
+			Timeout: 2 * time.Second,
+		}, {
+			Url:     "ws://127.0.0.1:8080/socket",
+			Payload: []byte{0x00, 0xff},
+			Binary:  true,
+			Timeout: 5 * time.Second,
+		}}, actualOptions.WebsocketChecks)
+	}
+
+	testCases := []struct {
+		name        string
+		spec        string
+		expectedErr string
+	}{
+		{"missing url", "send=ping", "missing required key \"url\""},
+		{"http url", "url=http://127.0.0.1:8080/socket", "must be a ws:// or wss:// URL"},
+		{"invalid regex", "url=ws://127.0.0.1:8080/socket,expect-regex=(", "is not a valid regular expression"},
+	}
+
+	for _, testCase := range testCases {
+		_, actualErr := parseOptions(createContextForTesting([]string{"--websocket", testCase.spec}))
+		if assert.NotNil(t, actualErr, testCase.name) {
+			assert.Contains(t, actualErr.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestParseStarlarkChecks(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "health-checker-starlark-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	valid := filepath.Join(dir, "replication.star")
+	invalid := filepath.Join(dir, "invalid.star")
+	undefined := filepath.Join(dir, "undefined.star")
+	for path, contents := range map[string]string{
+		valid:     "def check():\n    pass\n",
+		invalid:   "def check(:\n",
+		undefined: "def check():\n    if not tcp_dail(\"127.0.0.1:5432\").ok:\n        fail(\"down\")\n",
+	} {
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			assert.FailNow(t, "Failed to write script: %v", err.Error())
+		}
+	}
+
+	context := createContextForTesting([]string{"--starlark", "file=" + valid + ",timeout=2s,max-steps=5000", "--starlark", "file=" + valid + ",name=replica"})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) && assert.Equal(t, 2, len(actualOptions.StarlarkChecks)) {
+		// The compiled programs can't be compared, so only check that there is one
+		for i := range actualOptions.StarlarkChecks {
+			assert.NotNil(t, actualOptions.StarlarkChecks[i].Program)
+			actualOptions.StarlarkChecks[i].Program = nil
+		}
+		assert.Equal(t, []options.StarlarkCheck{
+			{Name: "replication", Path: valid, MaxSteps: 5000, Timeout: 2 * time.Second},
+			{Name: "replica", Path: valid, MaxSteps: 1000000, Timeout: 5 * time.Second},
+		}, actualOptions.StarlarkChecks)
+	}
+
+	testCases := []struct {
+		name        string
+		spec        string
+		expectedErr string
+	}{
+		{"missing file key", "timeout=2s", "missing required key \"file\""},
+		{"missing file", "file=" + filepath.Join(dir, "missing.star"), "no such file or directory"},
+		{"syntax error", "file=" + invalid, "invalid.star:1:12: got ':', want ')'"},
+		{"undefined name", "file=" + undefined, "undefined.star:2:12: undefined: tcp_dail"},
+		{"invalid max steps", "file=" + valid + ",max-steps=0", "must be a positive integer"},
+	}
+
+	for _, testCase := range testCases {
+		_, actualErr := parseOptions(createContextForTesting([]string{"--starlark", testCase.spec}))
+		if assert.NotNil(t, actualErr, testCase.name) {
+			assert.Contains(t, actualErr.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestParseDiskChecks(t *testing.T) {
+	t.Parallel()
+
+	context := createContextForTesting([]string{"--disk", "path=/var,warn-used-percent=80,fail-used-percent=95.5,warn-free-bytes=10G,fail-free-bytes=512M,fail-free-inodes=1000"})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) {
+		assert.Equal(t, []options.DiskCheck{{
+			Path:        "/var",
+			UsedPercent: options.Thresholds{Warn: 80, Fail: 95.5},
+			FreeBytes:   options.Thresholds{Warn: 10 << 30, Fail: 512 << 20},
+			FreeInodes:  options.Thresholds{Fail: 1000},
+		}}, actualOptions.DiskChecks)
+	}
+
+	_, actualErr = parseOptions(createContextForTesting([]string{"--disk", "path=/,fail-free-bytes=lots"}))
+	if assert.NotNil(t, actualErr) {
+		assert.Contains(t, actualErr.Error(), "must be a number of bytes")
+	}
+
+	_, actualErr = parseOptions(createContextForTesting([]string{"--disk", "path=/,fail-free-inodes=-1"}))
+	if assert.NotNil(t, actualErr) {
+		assert.Contains(t, actualErr.Error(), "must be a number that is not negative")
+	}
+}
+
+func TestParsePressureChecks(t *testing.T) {
+	t.Parallel()
+
+	context := createContextForTesting([]string{"--pressure", "resource=memory,kind=full,window=avg60,warn-stalled-percent=5,fail-stalled-percent=20"})
+	actualOptions, actualErr := parseOptions(context)
+	if assert.Nil(t, actualErr) {
+		assert.Equal(t, []options.PressureCheck{{
+			Resource: "memory",
+			Kind:     "full",
+			Window:   "avg60",
+			Stalled:  options.Thresholds{Warn: 5, Fail: 20},
+		}}, actualOptions.PressureChecks)
+	}
+
+	_, actualErr = parseOptions(createContextForTesting([]string{"--pressure", "resource=cpu,fail-stalled-percent=-1"}))
+	if assert.NotNil(t, actualErr) {
+		assert.Contains(t, actualErr.Error(), "must be a number that is not negative")
+	}
+}
+
 func defaultListener() string {
 	return test.ListenerString(DEFAULT_LISTENER_IP_ADDRESS, DEFAULT_LISTENER_PORT)
 }
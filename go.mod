@@ -3,10 +3,13 @@ module github.com/gruntwork-io/health-check
 go 1.14
 
 require (
+	github.com/go-sql-driver/mysql v1.7.1
 	github.com/gruntwork-io/go-commons v0.10.0
 	github.com/gruntwork-io/health-checker v0.0.5
+	github.com/lib/pq v1.10.9
 	github.com/sirupsen/logrus v1.6.0
 	github.com/stretchr/testify v1.6.1
 	github.com/urfave/cli v1.22.4
+	go.starlark.net v0.0.0-20230302034142-4b1e35fe2254
 	golang.org/x/sync v0.0.0-20201020160332-67f06af15bc9
 )
@@ -0,0 +1,38 @@
+package options
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The operators that compare numbers, which JSON and metric assertions share. Each operator comes before any shorter
+// operator that it starts with, so that <= is matched before < in an assertion without a space before the value.
+var NumericAssertionOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// A reference to another value in the same document, such as ${max_connections} or ${process_max_fds}
+var assertionReference = regexp.MustCompile(`^\$\{(.+)\}$`)
+
+// Return true if actual compares to expected as the given numeric operator requires, such as actual >= expected
+func CompareNumbers(actual float64, operator string, expected float64) bool {
+	switch operator {
+	case "==":
+		return actual == expected
+	case "!=":
+		return actual != expected
+	case "<":
+		return actual < expected
+	case "<=":
+		return actual <= expected
+	case ">":
+		return actual > expected
+	case ">=":
+		return actual >= expected
+	}
+	return false
+}
+
+// Describe an operator that isn't one of the given operators, for use in the error of an invalid assertion
+func unknownAssertionOperator(operator string, operators []string) string {
+	return fmt.Sprintf("unknown operator \"%s\", must be one of: %s", operator, strings.Join(operators, ", "))
+}
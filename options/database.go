@@ -0,0 +1,133 @@
+package options
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The replication roles that a database check can require
+var DatabaseRoles = []string{"primary", "replica"}
+
+// A check that performs the PostgreSQL startup handshake, and optionally logs in and runs a query
+type PostgresCheck struct {
+	// The host:port of the server
+	Address string
+	// If set, the check logs in as this user and queries the server's version and whether it is in recovery
+	User string
+	// The password to log in with, read from the file given by the password-file key
+	Password string
+	// The database to connect to
+	Database string
+	// The sslmode to log in with, as understood by libpq
+	SslMode string
+	// If not empty, the replication role of the server must be one of these
+	Roles []string
+	// See Spec.Negate
+	Negate bool
+}
+
+// A check that reads the MySQL server handshake, and optionally logs in and runs a query
+type MysqlCheck struct {
+	// The host:port of the server
+	Address string
+	// If set, the check logs in as this user and queries the server's version and whether it is a replica
+	User string
+	// The password to log in with, read from the file given by the password-file key
+	Password string
+	// The database to connect to
+	Database string
+	// If not empty, the replication role of the server must be one of these
+	Roles []string
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse PostgreSQL checks from specs of the form "address=127.0.0.1:5432" or
+// "address=127.0.0.1:5432,user=health,password-file=/etc/health-checker/pg-password,role=primary"
+func ParsePostgresChecks(specs []string) ([]PostgresCheck, error) {
+	rv := []PostgresCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "address", "user", "password", "password-file", "database", "sslmode", "role")
+		if err != nil {
+			return nil, err
+		}
+
+		password, roles, err := parseDatabaseSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, PostgresCheck{
+			Address:  spec.String("address", "127.0.0.1:5432"),
+			User:     spec.String("user", ""),
+			Password: password,
+			Database: spec.String("database", "postgres"),
+			SslMode:  spec.String("sslmode", "disable"),
+			Roles:    roles,
+			Negate:   negate,
+		})
+	}
+	return rv, nil
+}
+
+// Parse MySQL checks from specs of the form "address=127.0.0.1:3306" or
+// "address=127.0.0.1:3306,user=health,password-file=/etc/health-checker/mysql-password,role=replica"
+func ParseMysqlChecks(specs []string) ([]MysqlCheck, error) {
+	rv := []MysqlCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "address", "user", "password", "password-file", "database", "role")
+		if err != nil {
+			return nil, err
+		}
+
+		password, roles, err := parseDatabaseSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, MysqlCheck{
+			Address:  spec.String("address", "127.0.0.1:3306"),
+			User:     spec.String("user", ""),
+			Password: password,
+			Database: spec.String("database", ""),
+			Roles:    roles,
+			Negate:   negate,
+		})
+	}
+	return rv, nil
+}
+
+// Parse the password and roles that PostgreSQL and MySQL checks have in common. The password can only be read from a
+// file, because a password on the command line shows up in the process list of every user on the host. The role can
+// only be queried once logged in, so it requires a user.
+func parseDatabaseSpec(spec *Spec) (string, []string, error) {
+	password, err := spec.Secret("password")
+	if err != nil {
+		return "", nil, err
+	}
+
+	roles := []string{}
+	for _, role := range spec.Strings("role") {
+		role = strings.ToLower(role)
+		if !containsString(DatabaseRoles, role) {
+			return "", nil, InvalidSpec{spec.raw, fmt.Sprintf("unknown role \"%s\", must be one of: %s", role, strings.Join(DatabaseRoles, ", "))}
+		}
+		roles = append(roles, role)
+	}
+
+	if !spec.Has("user") && (len(roles) > 0 || password != "") {
+		return "", nil, InvalidSpec{spec.raw, "\"role\" and \"password-file\" require \"user\""}
+	}
+
+	return password, roles, nil
+}
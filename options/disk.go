@@ -0,0 +1,67 @@
+package options
+
+// A check on the space and inodes used by the filesystem mounted at a path
+type DiskCheck struct {
+	// A path on the filesystem to check, typically its mount point
+	Path string
+	// The maximum percentage of space that may be used
+	UsedPercent Thresholds
+	// The minimum number of bytes that must be free
+	FreeBytes Thresholds
+	// The minimum number of inodes that must be free
+	FreeInodes Thresholds
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse disk checks from specs of the form "path=/,warn-used-percent=80,fail-used-percent=95,fail-free-bytes=1G"
+func ParseDiskChecks(specs []string) ([]DiskCheck, error) {
+	rv := []DiskCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "path", "warn-used-percent", "fail-used-percent", "warn-free-bytes", "fail-free-bytes", "warn-free-inodes", "fail-free-inodes")
+		if err != nil {
+			return nil, err
+		}
+
+		path, err := spec.RequiredString("path")
+		if err != nil {
+			return nil, err
+		}
+
+		usedPercent, err := spec.Thresholds("used-percent")
+		if err != nil {
+			return nil, err
+		}
+
+		freeBytes, err := parseBytesThresholds(spec, "free-bytes")
+		if err != nil {
+			return nil, err
+		}
+
+		freeInodes, err := spec.Thresholds("free-inodes")
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, DiskCheck{Path: path, UsedPercent: usedPercent, FreeBytes: freeBytes, FreeInodes: freeInodes, Negate: negate})
+	}
+	return rv, nil
+}
+
+// Return the thresholds set with the keys warn-<name> and fail-<name>, which are numbers of bytes
+func parseBytesThresholds(spec *Spec, name string) (Thresholds, error) {
+	warn, err := spec.Bytes("warn-"+name, 0)
+	if err != nil {
+		return Thresholds{}, err
+	}
+	fail, err := spec.Bytes("fail-"+name, 0)
+	if err != nil {
+		return Thresholds{}, err
+	}
+	return Thresholds{Warn: float64(warn), Fail: float64(fail)}, nil
+}
@@ -0,0 +1,74 @@
+package options
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// The DNS record types that a DNS check can resolve
+var DnsRecordTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "TXT"}
+
+// A check that resolves a name via DNS
+type DnsCheck struct {
+	// The name to resolve
+	Name string
+	// The DNS server to query, as host:port. If empty, the system resolver is used.
+	Server string
+	// The type of record to look up. One of DnsRecordTypes.
+	RecordType string
+	// If set, each of these values must appear in the answers
+	ExpectedAnswers []string
+	// If greater than zero, the lookup fails if it takes longer than this
+	MaxLatency time.Duration
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse DNS checks from specs of the form "name=example.com,server=127.0.0.1:53,type=A,expect=1.2.3.4,max-latency=100ms"
+func ParseDnsChecks(specs []string) ([]DnsCheck, error) {
+	rv := []DnsCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "name", "server", "type", "expect", "max-latency")
+		if err != nil {
+			return nil, err
+		}
+
+		name, err := spec.RequiredString("name")
+		if err != nil {
+			return nil, err
+		}
+
+		recordType := strings.ToUpper(spec.String("type", "A"))
+		if !containsString(DnsRecordTypes, recordType) {
+			return nil, InvalidSpec{s, fmt.Sprintf("unsupported record type \"%s\", must be one of: %s", recordType, strings.Join(DnsRecordTypes, ", "))}
+		}
+
+		// Default to the standard DNS port if the server doesn't specify one
+		server := spec.String("server", "")
+		if _, _, err := net.SplitHostPort(server); server != "" && err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+
+		maxLatency, err := spec.Duration("max-latency", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, DnsCheck{
+			Name:            name,
+			Server:          server,
+			RecordType:      recordType,
+			ExpectedAnswers: spec.Strings("expect"),
+			MaxLatency:      maxLatency,
+			Negate:          negate,
+		})
+	}
+	return rv, nil
+}
@@ -0,0 +1,66 @@
+package options
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A check that containers identified by name or label are running and healthy, using the Docker Engine API
+type DockerCheck struct {
+	// The path of the Docker Engine API socket
+	Socket string
+	// The exact name of the container
+	Name string
+	// Labels of the form key=value, or just key, that a container must have
+	Labels []string
+	// The minimum number of matching containers that must be healthy
+	Min int
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse Docker checks from specs of the form "name=web" or "label=com.example.service=api,min=2"
+func ParseDockerChecks(specs []string) ([]DockerCheck, error) {
+	rv := []DockerCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "socket", "name", "label", "min")
+		if err != nil {
+			return nil, err
+		}
+
+		if !spec.Has("name") && !spec.Has("label") {
+			return nil, InvalidSpec{s, "at least one of \"name\" and \"label\" is required"}
+		}
+
+		min, err := spec.Int("min", 1)
+		if err != nil || min < 1 {
+			return nil, spec.invalidValue("min", "a positive integer")
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, DockerCheck{
+			Socket: spec.String("socket", "/var/run/docker.sock"),
+			Name:   strings.TrimPrefix(spec.String("name", ""), "/"),
+			Labels: spec.Strings("label"),
+			Min:    min,
+			Negate: negate,
+		})
+	}
+	return rv, nil
+}
+
+// Describe the containers that the check identifies, for use in log output
+func (docker DockerCheck) String() string {
+	descriptions := []string{}
+	if docker.Name != "" {
+		descriptions = append(descriptions, fmt.Sprintf("named %s", docker.Name))
+	}
+	if len(docker.Labels) > 0 {
+		descriptions = append(descriptions, fmt.Sprintf("with labels %s", strings.Join(docker.Labels, ", ")))
+	}
+	return strings.Join(descriptions, " and ")
+}
@@ -0,0 +1,84 @@
+package options
+
+import (
+	"regexp"
+	"time"
+)
+
+// A check that a file exists and, optionally, that it was modified recently and has the expected size and contents
+type FileCheck struct {
+	// The path of the file
+	Path string
+	// If greater than zero, the file must have been modified within this long
+	MaxAge time.Duration
+	// The minimum size of the file, in bytes
+	MinSize uint64
+	// If greater than zero, the maximum size of the file, in bytes
+	MaxSize uint64
+	// If set, the contents of the file must match this pattern
+	Match *regexp.Regexp
+	// If set, the file must contain a JSON document with a field at this dot separated path (e.g. status.state)
+	JsonField string
+	// The value the field at JsonField must have
+	JsonValue string
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse file checks from specs of the form "path=/var/run/agent.heartbeat,max-age=5m,min-size=1" or
+// "path=/var/run/agent.json,json-field=status.state,json-value=ok"
+func ParseFileChecks(specs []string) ([]FileCheck, error) {
+	rv := []FileCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "path", "max-age", "min-size", "max-size", "match", "json-field", "json-value")
+		if err != nil {
+			return nil, err
+		}
+
+		path, err := spec.RequiredString("path")
+		if err != nil {
+			return nil, err
+		}
+
+		maxAge, err := spec.Duration("max-age", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		minSize, err := spec.Bytes("min-size", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		maxSize, err := spec.Bytes("max-size", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		match, err := spec.Regexp("match")
+		if err != nil {
+			return nil, err
+		}
+
+		if spec.Has("json-field") != spec.Has("json-value") {
+			return nil, InvalidSpec{s, "\"json-field\" and \"json-value\" must be set together"}
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, FileCheck{
+			Path:      path,
+			MaxAge:    maxAge,
+			MinSize:   minSize,
+			MaxSize:   maxSize,
+			Match:     match,
+			JsonField: spec.String("json-field", ""),
+			JsonValue: spec.String("json-value", ""),
+			Negate:    negate,
+		})
+	}
+	return rv, nil
+}
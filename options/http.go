@@ -0,0 +1,103 @@
+package options
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A check that sends an HTTP request and checks the status code and, optionally, assertions about a JSON response body
+type HttpCheck struct {
+	// The URL to request
+	Url string
+	// If set, the request is sent over the Unix domain socket at this path instead of to the host in the URL. A path
+	// starting with @ is a socket in the abstract namespace.
+	UnixSocket string
+	// The HTTP method to use
+	Method string
+	// The status codes that count as success. If empty, any 2xx status code does.
+	ExpectStatus []int
+	// How long to wait for the whole request, including reading the response body
+	Timeout time.Duration
+	// Assertions about the response body, which must be a JSON document if any are set
+	JsonAssertions []JsonAssertion
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse HTTP checks from specs of the form "url=http://127.0.0.1:9200/_cluster/health,expect-json=status in green|yellow,warn-json=status == green"
+func ParseHttpChecks(specs []string) ([]HttpCheck, error) {
+	rv := []HttpCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "url", "unix-socket", "method", "status", "timeout", "expect-json", "warn-json")
+		if err != nil {
+			return nil, err
+		}
+
+		rawUrl, err := spec.RequiredString("url")
+		if err != nil {
+			return nil, err
+		}
+		if u, err := url.Parse(rawUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, spec.invalidValue("url", "an http:// or https:// URL")
+		}
+
+		method := strings.ToUpper(spec.String("method", http.MethodGet))
+
+		expectStatus := []int{}
+		for _, status := range spec.Strings("status") {
+			code, err := strconv.Atoi(status)
+			if err != nil || code < 100 || code > 599 {
+				return nil, InvalidSpec{s, fmt.Sprintf("the value of \"status\" must be an HTTP status code but got \"%s\"", status)}
+			}
+			expectStatus = append(expectStatus, code)
+		}
+
+		timeout, err := spec.Duration("timeout", 5*time.Second)
+		if err != nil {
+			return nil, err
+		}
+
+		assertions, err := spec.JsonAssertions("expect-json", "warn-json")
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, HttpCheck{
+			Url:            rawUrl,
+			UnixSocket:     spec.String("unix-socket", ""),
+			Method:         method,
+			ExpectStatus:   expectStatus,
+			Timeout:        timeout,
+			JsonAssertions: assertions,
+			Negate:         negate,
+		})
+	}
+	return rv, nil
+}
+
+// Return true if the given status code counts as success
+func (check HttpCheck) MatchesStatus(code int) bool {
+	return matchesStatus(check.ExpectStatus, code)
+}
+
+// Return true if code is one of the expected status codes, or if none are expected, if it is a 2xx code
+func matchesStatus(expectStatus []int, code int) bool {
+	if len(expectStatus) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, expected := range expectStatus {
+		if code == expected {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package options
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The operators that a JSON assertion can use. Only the numeric operators other than == and != compare numbers; those
+// two compare the value as a string.
+var JsonAssertionOperators = append(append([]string{}, NumericAssertionOperators...), "in", "exists")
+
+// An assertion about the value at a path in a JSON document, such as "status == green" or "active_shards >= 10"
+type JsonAssertion struct {
+	// The dot separated path to the value, such as status or nodes.0.name
+	Path string
+	// One of JsonAssertionOperators
+	Operator string
+	// The values to compare against. Only the in operator uses more than one, and exists uses none.
+	Values []string
+	// If true, a check whose assertion doesn't hold is degraded instead of failed
+	Warn bool
+}
+
+// Parse an assertion of the form "<path> <operator> <value>", or "<path> exists". The in operator takes a list of values
+// separated by |, such as "status in green|yellow". Numeric operators require the value to be a number, or a reference
+// to another path in the same document, such as "curr_connections < ${max_connections}".
+func ParseJsonAssertion(s string, warn bool) (JsonAssertion, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return JsonAssertion{}, InvalidJsonAssertion{s, "expected <path> <operator> <value>"}
+	}
+
+	assertion := JsonAssertion{Path: fields[0], Operator: fields[1], Warn: warn}
+	if !containsString(JsonAssertionOperators, assertion.Operator) {
+		return JsonAssertion{}, InvalidJsonAssertion{s, unknownAssertionOperator(assertion.Operator, JsonAssertionOperators)}
+	}
+
+	if assertion.Operator == "exists" {
+		if len(fields) != 2 {
+			return JsonAssertion{}, InvalidJsonAssertion{s, "exists does not take a value"}
+		}
+		return assertion, nil
+	}
+
+	// The value is everything after the operator, so that it may contain spaces
+	value := strings.TrimSpace(s)
+	value = strings.TrimSpace(value[len(assertion.Path):])
+	value = strings.TrimSpace(value[len(assertion.Operator):])
+	if len(fields) < 3 || value == "" {
+		return JsonAssertion{}, InvalidJsonAssertion{s, fmt.Sprintf("%s requires a value", assertion.Operator)}
+	}
+
+	switch assertion.Operator {
+	case "in":
+		assertion.Values = strings.Split(value, "|")
+	case "<", "<=", ">", ">=":
+		if _, err := strconv.ParseFloat(value, 64); err != nil && !assertionReference.MatchString(value) {
+			return JsonAssertion{}, InvalidJsonAssertion{s, fmt.Sprintf("%s requires a number or a ${path} reference but got \"%s\"", assertion.Operator, value)}
+		}
+		assertion.Values = []string{value}
+	default:
+		assertion.Values = []string{value}
+	}
+
+	return assertion, nil
+}
+
+// Parse the JSON assertions set with the given keys in a spec. Assertions set with warnKey only degrade the check.
+func (spec *Spec) JsonAssertions(failKey string, warnKey string) ([]JsonAssertion, error) {
+	assertions := []JsonAssertion{}
+	for _, key := range []string{failKey, warnKey} {
+		for _, s := range spec.Strings(key) {
+			assertion, err := ParseJsonAssertion(s, key == warnKey)
+			if err != nil {
+				return nil, InvalidSpec{spec.raw, err.Error()}
+			}
+			assertions = append(assertions, assertion)
+		}
+	}
+	return assertions, nil
+}
+
+// Return the path that the value of a numeric assertion refers to, if it is a reference such as ${max_connections}
+func (assertion JsonAssertion) Reference() (string, bool) {
+	if len(assertion.Values) != 1 {
+		return "", false
+	}
+	match := assertionReference.FindStringSubmatch(assertion.Values[0])
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+func (assertion JsonAssertion) String() string {
+	if assertion.Operator == "exists" {
+		return fmt.Sprintf("%s exists", assertion.Path)
+	}
+	return fmt.Sprintf("%s %s %s", assertion.Path, assertion.Operator, strings.Join(assertion.Values, "|"))
+}
+
+// Custom error types
+
+type InvalidJsonAssertion struct {
+	assertion string
+	reason    string
+}
+
+func (err InvalidJsonAssertion) Error() string {
+	return fmt.Sprintf("invalid JSON assertion \"%s\": %s", err.assertion, err.reason)
+}
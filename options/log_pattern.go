@@ -0,0 +1,63 @@
+package options
+
+import (
+	"regexp"
+	"time"
+)
+
+// A check that tails a log file and counts the lines that match a pattern, such as OutOfMemoryError, within a sliding
+// time window
+type LogPatternCheck struct {
+	// The path of the log file
+	Path string
+	// The pattern that lines are matched against
+	Match *regexp.Regexp
+	// How long a matching line counts against the check after it was read
+	Window time.Duration
+	// The check fails if more than this many lines matched within the window
+	Max int
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse log pattern checks from specs of the form "path=/var/log/app.log,match=OutOfMemoryError|FATAL,window=5m,max=0"
+func ParseLogPatternChecks(specs []string) ([]LogPatternCheck, error) {
+	rv := []LogPatternCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "path", "match", "window", "max")
+		if err != nil {
+			return nil, err
+		}
+
+		path, err := spec.RequiredString("path")
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := spec.RequiredString("match"); err != nil {
+			return nil, err
+		}
+		match, err := spec.Regexp("match")
+		if err != nil {
+			return nil, err
+		}
+
+		window, err := spec.Duration("window", 5*time.Minute)
+		if err != nil {
+			return nil, err
+		}
+
+		max, err := spec.Int("max", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, LogPatternCheck{Path: path, Match: match, Window: window, Max: max, Negate: negate})
+	}
+	return rv, nil
+}
@@ -1,6 +1,8 @@
 package options
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -8,17 +10,81 @@ import (
 
 // The options accepted by this CLI tool
 type Options struct {
-	Ports         []int
-	Scripts       []Script
-	ScriptTimeout int
-	Singleflight  bool
-	Listener      string
-	Logger        *logrus.Logger
+	Ports                []int
+	TcpChecks            []TcpCheck
+	Scripts              []Script
+	DnsChecks            []DnsCheck
+	UdpChecks            []UdpCheck
+	ZookeeperChecks      []ZookeeperCheck
+	TlsChecks            []TlsCheck
+	DiskChecks           []DiskCheck
+	LoadChecks           []LoadCheck
+	MemoryChecks         []MemoryCheck
+	PressureChecks       []PressureCheck
+	ProcessChecks        []ProcessCheck
+	SocketOwnerChecks    []SocketOwnerCheck
+	QuorumChecks         []QuorumCheck
+	FileChecks           []FileCheck
+	LogPatternChecks     []LogPatternCheck
+	HttpChecks           []HttpCheck
+	ScenarioChecks       []ScenarioCheck
+	UnixChecks           []UnixCheck
+	DockerChecks         []DockerCheck
+	RedisChecks          []RedisCheck
+	PostgresChecks       []PostgresCheck
+	MysqlChecks          []MysqlCheck
+	MemcachedChecks      []StatsCheck
+	StatsChecks          []StatsCheck
+	PrometheusChecks     []PrometheusCheck
+	PingChecks           []PingCheck
+	WebsocketChecks      []WebsocketCheck
+	StarlarkChecks       []StarlarkCheck
+	ScriptTimeout        int
+	ProcRoot             string
+	Singleflight         bool
+	Listener             string
+	HaproxyAgentListener string
+	Logger               *logrus.Logger
+}
+
+// Return the total number of checks configured
+func (opts *Options) NumChecks() int {
+	return len(opts.Ports) +
+		len(opts.TcpChecks) +
+		len(opts.Scripts) +
+		len(opts.DnsChecks) +
+		len(opts.UdpChecks) +
+		len(opts.ZookeeperChecks) +
+		len(opts.TlsChecks) +
+		len(opts.DiskChecks) +
+		len(opts.LoadChecks) +
+		len(opts.MemoryChecks) +
+		len(opts.PressureChecks) +
+		len(opts.ProcessChecks) +
+		len(opts.SocketOwnerChecks) +
+		len(opts.QuorumChecks) +
+		len(opts.FileChecks) +
+		len(opts.LogPatternChecks) +
+		len(opts.HttpChecks) +
+		len(opts.ScenarioChecks) +
+		len(opts.UnixChecks) +
+		len(opts.DockerChecks) +
+		len(opts.RedisChecks) +
+		len(opts.PostgresChecks) +
+		len(opts.MysqlChecks) +
+		len(opts.MemcachedChecks) +
+		len(opts.StatsChecks) +
+		len(opts.PrometheusChecks) +
+		len(opts.PingChecks) +
+		len(opts.WebsocketChecks) +
+		len(opts.StarlarkChecks)
 }
 
 type Script struct {
 	Name string
 	Args []string
+	// If true, the check passes when the script fails, and fails when the script succeeds
+	Negate bool
 }
 
 func ParseScripts(scriptStrings []string) []Script {
@@ -30,7 +96,73 @@ func ParseScripts(scriptStrings []string) []Script {
 		if len(commandArr) > 1 {
 			scriptParams = commandArr[1:]
 		}
-		rv = append(rv, Script{scriptName, scriptParams})
+		rv = append(rv, Script{Name: scriptName, Args: scriptParams})
 	}
 	return rv
 }
+
+// Parse scripts that are expected to fail, so that their checks pass only when the script exits with a non-zero status
+func ParseNegatedScripts(scriptStrings []string) []Script {
+	rv := ParseScripts(scriptStrings)
+	for i := range rv {
+		rv[i].Negate = true
+	}
+	return rv
+}
+
+// The most ports that a single range may cover, since each of them is dialed at the same time on every check
+const maxPortRangeSize = 256
+
+// Parse ports, each of which is either a single port such as "8000" or an inclusive range such as "8000-8007"
+func ParsePorts(portStrings []string) ([]int, error) {
+	rv := []int{}
+	for _, s := range portStrings {
+		ports, err := parsePortRange(s)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, ports...)
+	}
+	return rv, nil
+}
+
+func parsePortRange(s string) ([]int, error) {
+	bounds := strings.SplitN(strings.TrimSpace(s), "-", 2)
+
+	first, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return nil, InvalidPortRange(s)
+	}
+	last := first
+	if len(bounds) == 2 {
+		if last, err = strconv.Atoi(bounds[1]); err != nil {
+			return nil, InvalidPortRange(s)
+		}
+	}
+	if first < 1 || last > 65535 || first > last {
+		return nil, InvalidPortRange(s)
+	}
+	if last-first+1 > maxPortRangeSize {
+		return nil, PortRangeTooLarge(s)
+	}
+
+	ports := []int{}
+	for port := first; port <= last; port++ {
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// Custom error types
+
+type InvalidPortRange string
+
+func (s InvalidPortRange) Error() string {
+	return fmt.Sprintf("\"%s\" is not a port or a range of ports such as 8000-8007", string(s))
+}
+
+type PortRangeTooLarge string
+
+func (s PortRangeTooLarge) Error() string {
+	return fmt.Sprintf("\"%s\" covers more than %d ports", string(s), maxPortRangeSize)
+}
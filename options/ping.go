@@ -0,0 +1,88 @@
+package options
+
+import (
+	"time"
+)
+
+// A check that sends ICMP echo requests to a host and checks how many replies arrive and how quickly
+type PingCheck struct {
+	// The host name or IP address to ping
+	Host string
+	// If 4 or 6, the host is resolved to an address of that IP version only
+	IpVersion int
+	// The number of echo requests to send
+	Count int
+	// How long to wait between echo requests
+	Interval time.Duration
+	// How long to wait for the reply to each echo request
+	Timeout time.Duration
+	// The thresholds for the percentage of echo requests that got no reply
+	LossPercent Thresholds
+	// The thresholds for the average round trip time of the replies, in milliseconds
+	RttMs Thresholds
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse ping checks from specs of the form "host=10.0.0.1,count=5,fail-loss-percent=50,warn-rtt-ms=20"
+func ParsePingChecks(specs []string) ([]PingCheck, error) {
+	rv := []PingCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "host", "ip-version", "count", "interval", "timeout", "warn-loss-percent", "fail-loss-percent", "warn-rtt-ms", "fail-rtt-ms")
+		if err != nil {
+			return nil, err
+		}
+
+		host, err := spec.RequiredString("host")
+		if err != nil {
+			return nil, err
+		}
+
+		ipVersion, err := spec.Int("ip-version", 0)
+		if err != nil || (ipVersion != 0 && ipVersion != 4 && ipVersion != 6) {
+			return nil, spec.invalidValue("ip-version", "4 or 6")
+		}
+
+		count, err := spec.Int("count", 3)
+		if err != nil || count < 1 {
+			return nil, spec.invalidValue("count", "a positive integer")
+		}
+
+		interval, err := spec.Duration("interval", 200*time.Millisecond)
+		if err != nil {
+			return nil, err
+		}
+
+		timeout, err := spec.Duration("timeout", time.Second)
+		if err != nil {
+			return nil, err
+		}
+
+		lossPercent, err := spec.Thresholds("loss-percent")
+		if err != nil {
+			return nil, err
+		}
+
+		rttMs, err := spec.Thresholds("rtt-ms")
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, PingCheck{
+			Host:        host,
+			IpVersion:   ipVersion,
+			Count:       count,
+			Interval:    interval,
+			Timeout:     timeout,
+			LossPercent: lossPercent,
+			RttMs:       rttMs,
+			Negate:      negate,
+		})
+	}
+	return rv, nil
+}
@@ -0,0 +1,100 @@
+package options
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Identifies one or more processes, either by a pidfile or by matching their name or command line. When more than one
+// of these is set, a process must match all of them.
+type ProcessMatcher struct {
+	// A file containing the pid of the process
+	Pidfile string
+	// The exact name of the process, as shown in /proc/<pid>/comm
+	Name string
+	// A pattern that the command line of the process, with arguments separated by spaces, must match
+	Cmdline *regexp.Regexp
+}
+
+// Describe the processes that the matcher identifies, for use in log output
+func (matcher ProcessMatcher) String() string {
+	descriptions := []string{}
+	if matcher.Pidfile != "" {
+		descriptions = append(descriptions, fmt.Sprintf("with pidfile %s", matcher.Pidfile))
+	}
+	if matcher.Name != "" {
+		descriptions = append(descriptions, fmt.Sprintf("named %s", matcher.Name))
+	}
+	if matcher.Cmdline != nil {
+		descriptions = append(descriptions, fmt.Sprintf("with command line matching %q", matcher.Cmdline))
+	}
+	return strings.Join(descriptions, " and ")
+}
+
+// A check that the processes identified by a matcher are running
+type ProcessCheck struct {
+	Matcher ProcessMatcher
+	// The minimum number of matching processes
+	Min int
+	// If greater than zero, the maximum number of matching processes
+	Max int
+	// If true, the check fails if any matching process is a zombie
+	NoZombies bool
+	// If greater than zero, every matching process must have been running for at least this long
+	MinUptime time.Duration
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse process checks from specs of the form "cmdline=java .*kafka\.Kafka,min=1,max=1,no-zombies=true,min-uptime=30s"
+func ParseProcessChecks(specs []string) ([]ProcessCheck, error) {
+	rv := []ProcessCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "pidfile", "name", "cmdline", "min", "max", "no-zombies", "min-uptime")
+		if err != nil {
+			return nil, err
+		}
+
+		matcher, err := parseProcessMatcher(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		check := ProcessCheck{Matcher: matcher}
+		if check.Min, err = spec.Int("min", 1); err != nil {
+			return nil, err
+		}
+		if check.Max, err = spec.Int("max", 0); err != nil {
+			return nil, err
+		}
+		if check.NoZombies, err = spec.Bool("no-zombies", false); err != nil {
+			return nil, err
+		}
+		if check.MinUptime, err = spec.Duration("min-uptime", 0); err != nil {
+			return nil, err
+		}
+
+		if check.Negate, err = spec.Negate(); err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, check)
+	}
+	return rv, nil
+}
+
+// Parse the pidfile, name and cmdline keys of a spec, at least one of which must be set
+func parseProcessMatcher(spec *Spec) (ProcessMatcher, error) {
+	if !spec.Has("pidfile") && !spec.Has("name") && !spec.Has("cmdline") {
+		return ProcessMatcher{}, InvalidSpec{spec.raw, "one of \"pidfile\", \"name\" or \"cmdline\" is required"}
+	}
+
+	cmdline, err := spec.Regexp("cmdline")
+	if err != nil {
+		return ProcessMatcher{}, err
+	}
+
+	return ProcessMatcher{Pidfile: spec.String("pidfile", ""), Name: spec.String("name", ""), Cmdline: cmdline}, nil
+}
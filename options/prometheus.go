@@ -0,0 +1,261 @@
+package options
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A check that scrapes a Prometheus metrics endpoint in the text exposition format and makes assertions about the
+// values of its series
+type PrometheusCheck struct {
+	// The URL of the metrics endpoint
+	Url string
+	// If set, the request is sent over the Unix domain socket at this path instead of to the host in the URL
+	UnixSocket string
+	// How long to wait for the whole request, including reading the response body
+	Timeout time.Duration
+	// The assertions about the series
+	Assertions []MetricAssertion
+	// See Spec.Negate
+	Negate bool
+}
+
+// An assertion about the series with a name and labels, such as `queue_depth{queue="emails"} < 1000`. If more than one
+// series has the name and labels, for example because it has other labels too, the assertion must hold for every one.
+type MetricAssertion struct {
+	// The name of the metric
+	Name string
+	// The labels that the series must have. Any other labels are ignored.
+	Labels map[string]string
+	// One of NumericAssertionOperators
+	Operator string
+	// The value to compare against. Ignored if Reference is set.
+	Value float64
+	// If set, the value of the first series that this selects is compared against instead of Value
+	Reference *MetricSeries
+	// If true, a check whose assertion doesn't hold is degraded instead of failed
+	Warn bool
+}
+
+// A metric name and the labels that a series must have to be selected by it, such as process_max_fds{job="api"}
+type MetricSeries struct {
+	Name   string
+	Labels map[string]string
+}
+
+// Parse Prometheus checks from specs of the form
+// "url=http://127.0.0.1:9100/metrics,expect-metric=queue_depth{queue="emails"} < 1000,warn-metric=up == 1". The commas
+// between labels must be escaped with a backslash.
+func ParsePrometheusChecks(specs []string) ([]PrometheusCheck, error) {
+	rv := []PrometheusCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "url", "unix-socket", "timeout", "expect-metric", "warn-metric")
+		if err != nil {
+			return nil, err
+		}
+
+		rawUrl, err := spec.RequiredString("url")
+		if err != nil {
+			return nil, err
+		}
+		if u, err := url.Parse(rawUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, spec.invalidValue("url", "an http:// or https:// URL")
+		}
+
+		timeout, err := spec.Duration("timeout", 5*time.Second)
+		if err != nil {
+			return nil, err
+		}
+
+		assertions := []MetricAssertion{}
+		for _, key := range []string{"expect-metric", "warn-metric"} {
+			for _, value := range spec.Strings(key) {
+				assertion, err := ParseMetricAssertion(value, key == "warn-metric")
+				if err != nil {
+					return nil, InvalidSpec{s, err.Error()}
+				}
+				assertions = append(assertions, assertion)
+			}
+		}
+		if len(assertions) == 0 {
+			return nil, InvalidSpec{s, "at least one of \"expect-metric\" and \"warn-metric\" is required"}
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, PrometheusCheck{
+			Url:        rawUrl,
+			UnixSocket: spec.String("unix-socket", ""),
+			Timeout:    timeout,
+			Assertions: assertions,
+			Negate:     negate,
+		})
+	}
+	return rv, nil
+}
+
+// Parse an assertion of the form `<name>{<label>="<value>",...} <operator> <value>`, such as "up == 1" or
+// `queue_depth{queue="emails"} < 1000`. The labels are optional. The value is a number, or a reference to another
+// series in the same scrape, such as "process_open_fds < ${process_max_fds}".
+func ParseMetricAssertion(s string, warn bool) (MetricAssertion, error) {
+	name, labels, rest, err := ParseMetricSeries(strings.TrimSpace(s))
+	if err != nil {
+		return MetricAssertion{}, InvalidMetricAssertion{s, err.Error()}
+	}
+
+	assertion := MetricAssertion{Name: name, Labels: labels, Warn: warn}
+	rest = strings.TrimSpace(rest)
+	for _, operator := range NumericAssertionOperators {
+		if strings.HasPrefix(rest, operator) {
+			assertion.Operator = operator
+			break
+		}
+	}
+	if assertion.Operator == "" {
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return MetricAssertion{}, InvalidMetricAssertion{s, "expected <series> <operator> <value>"}
+		}
+		return MetricAssertion{}, InvalidMetricAssertion{s, unknownAssertionOperator(fields[0], NumericAssertionOperators)}
+	}
+
+	value := strings.TrimSpace(strings.TrimPrefix(rest, assertion.Operator))
+	if match := assertionReference.FindStringSubmatch(value); match != nil {
+		name, labels, rest, err := ParseMetricSeries(match[1])
+		if err == nil && rest == "" {
+			assertion.Reference = &MetricSeries{Name: name, Labels: labels}
+			return assertion, nil
+		}
+	} else if assertion.Value, err = strconv.ParseFloat(value, 64); err == nil {
+		return assertion, nil
+	}
+	return MetricAssertion{}, InvalidMetricAssertion{s, fmt.Sprintf("%s requires a number or a ${series} reference but got \"%s\"", assertion.Operator, value)}
+}
+
+// Parse the name and labels at the start of s, such as `http_requests_total{code="200",method="get"}`, which is how a
+// series is written both in the text exposition format and in a metric assertion. Returns the rest of s.
+func ParseMetricSeries(s string) (string, map[string]string, string, error) {
+	end := 0
+	for end < len(s) && isMetricNameChar(s[end], end == 0) {
+		end++
+	}
+	if end == 0 {
+		return "", nil, "", fmt.Errorf("expected a metric name")
+	}
+	name, rest := s[:end], s[end:]
+
+	labels := map[string]string{}
+	if !strings.HasPrefix(rest, "{") {
+		return name, labels, rest, nil
+	}
+	rest = rest[1:]
+
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		if strings.HasPrefix(rest, "}") {
+			return name, labels, rest[1:], nil
+		}
+
+		end = 0
+		for end < len(rest) && isMetricNameChar(rest[end], end == 0) && rest[end] != ':' {
+			end++
+		}
+		if end == 0 || !strings.HasPrefix(rest[end:], `="`) {
+			return "", nil, "", fmt.Errorf("expected label=\"value\" or } after %s", strings.TrimSuffix(s, rest))
+		}
+		label := rest[:end]
+		rest = rest[end+2:]
+
+		value := strings.Builder{}
+		for {
+			if rest == "" {
+				return "", nil, "", fmt.Errorf("the value of label %s is not terminated", label)
+			}
+			c := rest[0]
+			rest = rest[1:]
+			if c == '"' {
+				break
+			}
+			if c == '\\' && rest != "" {
+				c = rest[0]
+				rest = rest[1:]
+				if c == 'n' {
+					c = '\n'
+				}
+			}
+			value.WriteByte(c)
+		}
+		labels[label] = value.String()
+	}
+}
+
+func isMetricNameChar(c byte, first bool) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+// Return true if the series with the given name and labels is selected by the assertion
+func (assertion MetricAssertion) Selects(name string, labels map[string]string) bool {
+	return MetricSeries{Name: assertion.Name, Labels: assertion.Labels}.Selects(name, labels)
+}
+
+func (assertion MetricAssertion) String() string {
+	value := strconv.FormatFloat(assertion.Value, 'g', -1, 64)
+	if assertion.Reference != nil {
+		value = fmt.Sprintf("${%s}", assertion.Reference)
+	}
+	return fmt.Sprintf("%s %s %s", FormatMetricSeries(assertion.Name, assertion.Labels), assertion.Operator, value)
+}
+
+// Return true if the series with the given name and labels has this name and at least these labels
+func (series MetricSeries) Selects(name string, labels map[string]string) bool {
+	if name != series.Name {
+		return false
+	}
+	for label, value := range series.Labels {
+		if labels[label] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (series MetricSeries) String() string {
+	return FormatMetricSeries(series.Name, series.Labels)
+}
+
+// Format a series the way it is written in the text exposition format, with its labels sorted by name
+func FormatMetricSeries(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+
+	names := []string{}
+	for label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+
+	pairs := []string{}
+	for _, label := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", label, labels[label]))
+	}
+	return fmt.Sprintf("%s{%s}", name, strings.Join(pairs, ","))
+}
+
+// Custom error types
+
+type InvalidMetricAssertion struct {
+	assertion string
+	reason    string
+}
+
+func (err InvalidMetricAssertion) Error() string {
+	return fmt.Sprintf("invalid metric assertion \"%s\": %s", err.assertion, err.reason)
+}
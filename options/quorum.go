@@ -0,0 +1,87 @@
+package options
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A group of checks that passes when at least a minimum number of them pass, such as 6 out of a pool of 8 worker ports.
+// Only ports and scripts can be grouped.
+type QuorumCheck struct {
+	// A name for the group, used in log output
+	Name string
+	// The ports to open a TCP connection to, each of which is a check in the group
+	Ports []int
+	// The scripts to run, each of which is a check in the group
+	Scripts []Script
+	// The minimum number of checks that must pass. Ignored if MinPercent is set.
+	Min int
+	// The minimum percentage of checks that must pass
+	MinPercent float64
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse quorum checks from specs of the form "port=8000-8007,min=6" or "port=8000-8007,script=/bin/check.sh,min=75%"
+func ParseQuorumChecks(specs []string) ([]QuorumCheck, error) {
+	rv := []QuorumCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "name", "port", "script", "min")
+		if err != nil {
+			return nil, err
+		}
+
+		ports, err := ParsePorts(spec.Strings("port"))
+		if err != nil {
+			return nil, InvalidSpec{s, err.Error()}
+		}
+		scripts := ParseScripts(spec.Strings("script"))
+
+		total := len(ports) + len(scripts)
+		if total == 0 {
+			return nil, InvalidSpec{s, "at least one port or script is required"}
+		}
+
+		check := QuorumCheck{Ports: ports, Scripts: scripts, Min: total}
+		check.Name = spec.String("name", fmt.Sprintf("of %d checks", total))
+
+		if spec.Has("min") {
+			min := spec.String("min", "")
+			if strings.HasSuffix(min, "%") {
+				percent, err := strconv.ParseFloat(strings.TrimSuffix(min, "%"), 64)
+				if err != nil || percent <= 0 || percent > 100 {
+					return nil, spec.invalidValue("min", "a number of checks or a percentage between 0% and 100%")
+				}
+				check.MinPercent = percent
+			} else {
+				count, err := strconv.Atoi(min)
+				if err != nil || count < 1 || count > total {
+					return nil, spec.invalidValue("min", fmt.Sprintf("a number of checks between 1 and %d, or a percentage", total))
+				}
+				check.Min = count
+			}
+		}
+
+		if check.Negate, err = spec.Negate(); err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, check)
+	}
+	return rv, nil
+}
+
+// Return the number of checks in the group
+func (quorum QuorumCheck) Total() int {
+	return len(quorum.Ports) + len(quorum.Scripts)
+}
+
+// Return the number of checks that must pass for the group to pass
+func (quorum QuorumCheck) Required() int {
+	if quorum.MinPercent > 0 {
+		return int(math.Ceil(quorum.MinPercent * float64(quorum.Total()) / 100))
+	}
+	return quorum.Min
+}
@@ -0,0 +1,76 @@
+package options
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A check that talks to Redis using its protocol, and optionally asserts on the fields returned by INFO
+type RedisCheck struct {
+	// The host:port of the Redis server
+	Address string
+	// If set, AUTH is sent with this username, which requires Redis 6 or later
+	Username string
+	// If set, AUTH is sent with this password before any other command. It is read from the file given by the
+	// password-file key.
+	Password string
+	// If not empty, the role reported by INFO must be one of these (master or slave)
+	Roles []string
+	// Fields that INFO must report with the given values, such as master_link_status=up or loading=0
+	ExpectInfo map[string]string
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse Redis checks from specs of the form "address=127.0.0.1:6379,password-file=/etc/redis/password,role=slave,expect-info=master_link_status:up"
+func ParseRedisChecks(specs []string) ([]RedisCheck, error) {
+	rv := []RedisCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "address", "username", "password", "password-file", "role", "expect-info")
+		if err != nil {
+			return nil, err
+		}
+
+		password, err := spec.Secret("password")
+		if err != nil {
+			return nil, err
+		}
+
+		roles := []string{}
+		for _, role := range spec.Strings("role") {
+			// Redis reports replicas as slaves in INFO
+			role = strings.ToLower(role)
+			if role == "replica" {
+				role = "slave"
+			}
+			if role != "master" && role != "slave" {
+				return nil, InvalidSpec{s, fmt.Sprintf("unknown role \"%s\", must be one of: master, replica, slave", role)}
+			}
+			roles = append(roles, role)
+		}
+
+		expectInfo := map[string]string{}
+		for _, field := range spec.Strings("expect-info") {
+			keyAndValue := strings.SplitN(field, ":", 2)
+			if len(keyAndValue) != 2 {
+				return nil, InvalidSpec{s, fmt.Sprintf("expected field:value for \"expect-info\" but got \"%s\"", field)}
+			}
+			expectInfo[keyAndValue[0]] = keyAndValue[1]
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, RedisCheck{
+			Address:    spec.String("address", "127.0.0.1:6379"),
+			Username:   spec.String("username", ""),
+			Password:   password,
+			Roles:      roles,
+			ExpectInfo: expectInfo,
+			Negate:     negate,
+		})
+	}
+	return rv, nil
+}
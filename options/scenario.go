@@ -0,0 +1,228 @@
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Variables extracted by one step of a scenario are referenced by later steps as ${name}
+var scenarioVariable = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)\}`)
+var scenarioVariableName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// A check that runs a series of HTTP requests in order, such as logging in and then fetching data with the session
+// token returned by the login
+type ScenarioCheck struct {
+	// A name for the scenario, used in log output
+	Name string
+	// The steps to run, in order
+	Steps []ScenarioStep
+	// How long the whole scenario may take
+	Timeout time.Duration
+	// See Spec.Negate
+	Negate bool
+}
+
+// A single HTTP request in a scenario
+type ScenarioStep struct {
+	// An optional name for the step, used in errors
+	Name string
+	// The HTTP method to use
+	Method string
+	// The URL to request, which may reference variables
+	Url string
+	// Headers to send, whose values may reference variables
+	Headers map[string]string
+	// The request body, which may reference variables
+	Body string
+	// The status codes that count as success. If empty, any 2xx status code does.
+	ExpectStatus []int
+	// Assertions about the response body, which must be a JSON document if any are set
+	JsonAssertions []JsonAssertion
+	// Values to extract from the response, in order
+	Extractions []ScenarioExtraction
+}
+
+// Extracts a value from a response into a variable. The value is taken from the header named Header if it is set, or
+// from the field at JsonPath in the JSON response body if that is set, or else from the whole response body. If Regex
+// is set, it is applied to that value, and its first capture group, or the whole match if it has none, is extracted.
+type ScenarioExtraction struct {
+	Variable string
+	Header   string
+	JsonPath string
+	Regex    *regexp.Regexp
+}
+
+// The format of the file that defines a scenario
+type scenarioFile struct {
+	Name  string `json:"name"`
+	Steps []struct {
+		Name       string            `json:"name"`
+		Method     string            `json:"method"`
+		Url        string            `json:"url"`
+		Headers    map[string]string `json:"headers"`
+		Body       string            `json:"body"`
+		Status     []int             `json:"status"`
+		ExpectJson []string          `json:"expect-json"`
+		WarnJson   []string          `json:"warn-json"`
+		Extract    []struct {
+			Var    string `json:"var"`
+			Header string `json:"header"`
+			Json   string `json:"json"`
+			Regex  string `json:"regex"`
+		} `json:"extract"`
+	} `json:"steps"`
+}
+
+// Parse scenario checks from specs of the form "file=/etc/health-checker/login.json,timeout=10s". The steps of the
+// scenario are read from the file, which is documented in the README.
+func ParseScenarioChecks(specs []string) ([]ScenarioCheck, error) {
+	rv := []ScenarioCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "file", "timeout")
+		if err != nil {
+			return nil, err
+		}
+
+		path, err := spec.RequiredString("file")
+		if err != nil {
+			return nil, err
+		}
+
+		check, err := parseScenarioFile(path)
+		if err != nil {
+			return nil, InvalidSpec{s, err.Error()}
+		}
+
+		if check.Timeout, err = spec.Duration("timeout", 10*time.Second); err != nil {
+			return nil, err
+		}
+
+		if check.Negate, err = spec.Negate(); err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, check)
+	}
+	return rv, nil
+}
+
+func parseScenarioFile(path string) (ScenarioCheck, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ScenarioCheck{}, err
+	}
+
+	var file scenarioFile
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return ScenarioCheck{}, InvalidScenario{path, err.Error()}
+	}
+	if len(file.Steps) == 0 {
+		return ScenarioCheck{}, InvalidScenario{path, "at least one step is required"}
+	}
+
+	check := ScenarioCheck{Name: file.Name}
+	if check.Name == "" {
+		check.Name = filepath.Base(path)
+	}
+
+	defined := map[string]bool{}
+	for i, fileStep := range file.Steps {
+		step := ScenarioStep{
+			Name:         fileStep.Name,
+			Method:       strings.ToUpper(fileStep.Method),
+			Url:          fileStep.Url,
+			Headers:      fileStep.Headers,
+			Body:         fileStep.Body,
+			ExpectStatus: fileStep.Status,
+		}
+		label := fmt.Sprintf("step %d", i+1)
+		if step.Name != "" {
+			label = fmt.Sprintf("step %d (%s)", i+1, step.Name)
+		}
+		if step.Method == "" {
+			step.Method = http.MethodGet
+		}
+		if step.Url == "" {
+			return ScenarioCheck{}, InvalidScenario{path, fmt.Sprintf("%s has no url", label)}
+		}
+
+		// Only variables extracted by earlier steps may be referenced
+		references := []string{step.Url, step.Body}
+		for _, value := range step.Headers {
+			references = append(references, value)
+		}
+		for _, reference := range references {
+			for _, match := range scenarioVariable.FindAllStringSubmatch(reference, -1) {
+				if !defined[match[1]] {
+					return ScenarioCheck{}, InvalidScenario{path, fmt.Sprintf("%s references the variable %s before it is extracted", label, match[1])}
+				}
+			}
+		}
+
+		for _, s := range fileStep.ExpectJson {
+			assertion, err := ParseJsonAssertion(s, false)
+			if err != nil {
+				return ScenarioCheck{}, InvalidScenario{path, err.Error()}
+			}
+			step.JsonAssertions = append(step.JsonAssertions, assertion)
+		}
+		for _, s := range fileStep.WarnJson {
+			assertion, err := ParseJsonAssertion(s, true)
+			if err != nil {
+				return ScenarioCheck{}, InvalidScenario{path, err.Error()}
+			}
+			step.JsonAssertions = append(step.JsonAssertions, assertion)
+		}
+
+		for _, fileExtraction := range fileStep.Extract {
+			if !scenarioVariableName.MatchString(fileExtraction.Var) {
+				return ScenarioCheck{}, InvalidScenario{path, fmt.Sprintf("%s extracts a value without a valid var", label)}
+			}
+			if fileExtraction.Header != "" && fileExtraction.Json != "" {
+				return ScenarioCheck{}, InvalidScenario{path, fmt.Sprintf("%s extracts %s from both a header and a JSON path", label, fileExtraction.Var)}
+			}
+
+			extraction := ScenarioExtraction{Variable: fileExtraction.Var, Header: fileExtraction.Header, JsonPath: fileExtraction.Json}
+			if fileExtraction.Regex != "" {
+				if extraction.Regex, err = regexp.Compile(fileExtraction.Regex); err != nil {
+					return ScenarioCheck{}, InvalidScenario{path, fmt.Sprintf("%s has an invalid regex for %s: %s", label, fileExtraction.Var, err)}
+				}
+			}
+			step.Extractions = append(step.Extractions, extraction)
+			defined[extraction.Variable] = true
+		}
+
+		check.Steps = append(check.Steps, step)
+	}
+
+	return check, nil
+}
+
+// Return true if the given status code counts as success
+func (step ScenarioStep) MatchesStatus(code int) bool {
+	return matchesStatus(step.ExpectStatus, code)
+}
+
+// Replace each ${name} in s with the value of the variable
+func ExpandScenarioVariables(s string, variables map[string]string) string {
+	return scenarioVariable.ReplaceAllStringFunc(s, func(reference string) string {
+		return variables[scenarioVariable.FindStringSubmatch(reference)[1]]
+	})
+}
+
+// Custom error types
+
+type InvalidScenario struct {
+	path   string
+	reason string
+}
+
+func (err InvalidScenario) Error() string {
+	return fmt.Sprintf("invalid scenario in %s: %s", err.path, err.reason)
+}
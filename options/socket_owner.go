@@ -0,0 +1,40 @@
+package options
+
+// A check that the TCP socket listening on a port is owned by the expected process
+type SocketOwnerCheck struct {
+	// The port the socket is listening on
+	Port int
+	// Identifies the process that must own the socket
+	Matcher ProcessMatcher
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse socket owner checks from specs of the form "port=8080,pidfile=/var/run/app.pid"
+func ParseSocketOwnerChecks(specs []string) ([]SocketOwnerCheck, error) {
+	rv := []SocketOwnerCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "port", "pidfile", "name", "cmdline")
+		if err != nil {
+			return nil, err
+		}
+
+		port, err := spec.Port("port")
+		if err != nil {
+			return nil, err
+		}
+
+		matcher, err := parseProcessMatcher(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, SocketOwnerCheck{Port: port, Matcher: matcher, Negate: negate})
+	}
+	return rv, nil
+}
@@ -0,0 +1,297 @@
+package options
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Checks that need more than a single value are configured with a spec: a comma separated list of key=value pairs,
+// such as "name=example.com,server=127.0.0.1:53,type=A". A comma or backslash can be included in a value by escaping
+// it with a backslash. A key may be repeated when a check accepts more than one value for it.
+type Spec struct {
+	raw    string
+	values map[string][]string
+}
+
+// Parse the given spec, returning an error if it is malformed or uses a key that is not in allowedKeys. The negate key
+// is allowed in every spec.
+func ParseSpec(raw string, allowedKeys ...string) (*Spec, error) {
+	spec := &Spec{raw: raw, values: map[string][]string{}}
+	allowedKeys = append(allowedKeys, "negate")
+
+	for _, pair := range splitEscaped(raw, ',') {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		keyAndValue := strings.SplitN(pair, "=", 2)
+		if len(keyAndValue) != 2 {
+			return nil, InvalidSpec{raw, fmt.Sprintf("expected key=value but got \"%s\"", pair)}
+		}
+
+		key := strings.TrimSpace(keyAndValue[0])
+		if !containsString(allowedKeys, key) {
+			return nil, InvalidSpec{raw, fmt.Sprintf("unknown key \"%s\", must be one of: %s", key, strings.Join(allowedKeys, ", "))}
+		}
+
+		spec.values[key] = append(spec.values[key], keyAndValue[1])
+	}
+
+	return spec, nil
+}
+
+// Return true if the spec sets the given key
+func (spec *Spec) Has(key string) bool {
+	_, ok := spec.values[key]
+	return ok
+}
+
+// Return the value of the given key, or defaultValue if it isn't set. If the key is set more than once, the last
+// value wins.
+func (spec *Spec) String(key string, defaultValue string) string {
+	values := spec.values[key]
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values[len(values)-1]
+}
+
+// Return all the values of the given key, in the order they were set
+func (spec *Spec) Strings(key string) []string {
+	return spec.values[key]
+}
+
+// Return the value of the given key, which must be set
+func (spec *Spec) RequiredString(key string) (string, error) {
+	if !spec.Has(key) || spec.String(key, "") == "" {
+		return "", InvalidSpec{spec.raw, fmt.Sprintf("missing required key \"%s\"", key)}
+	}
+	return spec.String(key, ""), nil
+}
+
+// Return the value of the given key as an int, or defaultValue if it isn't set
+func (spec *Spec) Int(key string, defaultValue int) (int, error) {
+	if !spec.Has(key) {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(spec.String(key, ""))
+	if err != nil {
+		return 0, spec.invalidValue(key, "an integer")
+	}
+	return value, nil
+}
+
+// Return the value of the given key as a port number, which must be set
+func (spec *Spec) Port(key string) (int, error) {
+	if !spec.Has(key) {
+		return 0, InvalidSpec{spec.raw, fmt.Sprintf("missing required key \"%s\"", key)}
+	}
+	port, err := spec.Int(key, 0)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, spec.invalidValue(key, "a port number between 1 and 65535")
+	}
+	return port, nil
+}
+
+// Return the value of the given key as a float, or defaultValue if it isn't set
+func (spec *Spec) Float(key string, defaultValue float64) (float64, error) {
+	if !spec.Has(key) {
+		return defaultValue, nil
+	}
+	value, err := strconv.ParseFloat(spec.String(key, ""), 64)
+	if err != nil {
+		return 0, spec.invalidValue(key, "a number")
+	}
+	return value, nil
+}
+
+// Warning and failure thresholds for a measured value. Crossing the warning threshold degrades a check and crossing the
+// failure threshold fails it. A threshold of zero is not checked.
+type Thresholds struct {
+	Warn float64
+	Fail float64
+}
+
+// Return the thresholds set with the keys warn-<name> and fail-<name>, which may not be negative
+func (spec *Spec) Thresholds(name string) (Thresholds, error) {
+	values := []float64{}
+	for _, key := range []string{"warn-" + name, "fail-" + name} {
+		value, err := spec.Float(key, 0)
+		if err != nil || value < 0 {
+			return Thresholds{}, spec.invalidValue(key, "a number that is not negative")
+		}
+		values = append(values, value)
+	}
+	return Thresholds{Warn: values[0], Fail: values[1]}, nil
+}
+
+// Return the value of the given key as a bool, or defaultValue if it isn't set
+func (spec *Spec) Bool(key string, defaultValue bool) (bool, error) {
+	if !spec.Has(key) {
+		return defaultValue, nil
+	}
+	value, err := strconv.ParseBool(spec.String(key, ""))
+	if err != nil {
+		return false, spec.invalidValue(key, "true or false")
+	}
+	return value, nil
+}
+
+// Return the value of the negate key, which every spec accepts. A negated check passes when its condition is absent,
+// such as a port being closed, and fails when it is present. Each check keeps this in its Negate field.
+func (spec *Spec) Negate() (bool, error) {
+	return spec.Bool("negate", false)
+}
+
+// Return the value of the given key as a duration (e.g. 500ms or 2s), or defaultValue if it isn't set
+func (spec *Spec) Duration(key string, defaultValue time.Duration) (time.Duration, error) {
+	if !spec.Has(key) {
+		return defaultValue, nil
+	}
+	value, err := time.ParseDuration(spec.String(key, ""))
+	if err != nil {
+		return 0, spec.invalidValue(key, "a duration such as 500ms or 2s")
+	}
+	return value, nil
+}
+
+// Return the value of the given key as a number of bytes, or defaultValue if it isn't set. The value may have a K, M, G
+// or T suffix, which are powers of 1024 (e.g. 512M or 10G).
+func (spec *Spec) Bytes(key string, defaultValue uint64) (uint64, error) {
+	if !spec.Has(key) {
+		return defaultValue, nil
+	}
+
+	value := strings.TrimSuffix(strings.ToUpper(spec.String(key, "")), "B")
+	multiplier := uint64(1)
+	for i, suffix := range []string{"K", "M", "G", "T"} {
+		if strings.HasSuffix(value, suffix) {
+			multiplier = 1 << (10 * uint(i+1))
+			value = strings.TrimSuffix(value, suffix)
+			break
+		}
+	}
+
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, spec.invalidValue(key, "a number of bytes such as 1024, 512M or 10G")
+	}
+	return number * multiplier, nil
+}
+
+// Return a secret, such as a password, which is read from the file given by the key plus a "-file" suffix (e.g.
+// password-file), or an empty string if that isn't set. The key itself is rejected, because its value would show up in
+// the process list of every user on the host. Trailing newlines in the file are ignored.
+func (spec *Spec) Secret(key string) (string, error) {
+	fileKey := key + "-file"
+	if spec.Has(key) {
+		return "", InvalidSpec{spec.raw, fmt.Sprintf("\"%s\" would show up in the process list, use \"%s\" instead", key, fileKey)}
+	}
+
+	if !spec.Has(fileKey) {
+		return "", nil
+	}
+	contents, err := ioutil.ReadFile(spec.String(fileKey, ""))
+	if err != nil {
+		return "", InvalidSpec{spec.raw, err.Error()}
+	}
+	return strings.TrimRight(string(contents), "\r\n"), nil
+}
+
+// Return the value of the given key as a compiled regular expression, or nil if it isn't set
+func (spec *Spec) Regexp(key string) (*regexp.Regexp, error) {
+	if !spec.Has(key) {
+		return nil, nil
+	}
+	value, err := regexp.Compile(spec.String(key, ""))
+	if err != nil {
+		return nil, InvalidSpec{spec.raw, fmt.Sprintf("the value of \"%s\" is not a valid regular expression: %s", key, err)}
+	}
+	return value, nil
+}
+
+// Return the payload to send over the network, which is set either as text with the given key, or as hex with the
+// same key plus a "-hex" suffix (e.g. send or send-hex). Text payloads may contain Go escape sequences such as \r\n.
+func (spec *Spec) Payload(key string) ([]byte, error) {
+	hexKey := key + "-hex"
+	if spec.Has(key) && spec.Has(hexKey) {
+		return nil, InvalidSpec{spec.raw, fmt.Sprintf("only one of \"%s\" and \"%s\" may be set", key, hexKey)}
+	}
+
+	if spec.Has(hexKey) {
+		value, err := hex.DecodeString(strings.ReplaceAll(spec.String(hexKey, ""), " ", ""))
+		if err != nil {
+			return nil, spec.invalidValue(hexKey, "a hex string")
+		}
+		return value, nil
+	}
+
+	if !spec.Has(key) {
+		return nil, nil
+	}
+	value, err := strconv.Unquote(`"` + strings.ReplaceAll(spec.String(key, ""), `"`, `\"`) + `"`)
+	if err != nil {
+		return nil, spec.invalidValue(key, "text with valid escape sequences")
+	}
+	return []byte(value), nil
+}
+
+func (spec *Spec) invalidValue(key string, expected string) error {
+	return InvalidSpec{spec.raw, fmt.Sprintf("the value of \"%s\" must be %s but got \"%s\"", key, expected, spec.String(key, ""))}
+}
+
+// Split s on sep, unless sep is escaped with a backslash
+func splitEscaped(s string, sep rune) []string {
+	parts := []string{}
+	current := strings.Builder{}
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			if r != sep && r != '\\' {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == sep:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if escaped {
+		current.WriteRune('\\')
+	}
+
+	return append(parts, current.String())
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Custom error types
+
+type InvalidSpec struct {
+	spec   string
+	reason string
+}
+
+func (err InvalidSpec) Error() string {
+	return fmt.Sprintf("Invalid check \"%s\": %s", err.spec, err.reason)
+}
@@ -0,0 +1,94 @@
+package options
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// The names of the built-ins that the health checker gives a script, on top of those of the Starlark language. A script
+// that uses any other name is rejected when it is compiled.
+var StarlarkBuiltins = []string{"json", "struct", "tcp_dial", "http_get", "read_file", "warn", "detail"}
+
+// A check that runs a Starlark script in-process, for custom logic that would otherwise need a --script. The script
+// must define a check() function, which passes unless it calls fail(). The built-ins it may use are documented in the
+// README.
+type StarlarkCheck struct {
+	// A name for the script, used in log output
+	Name string
+	// The file the script was read from, used in errors
+	Path string
+	// The compiled script, which is initialized afresh every time the check runs
+	Program *starlark.Program
+	// The maximum number of steps the interpreter may take, which stops runaway loops
+	MaxSteps uint64
+	// How long the script may run, including the time its built-ins spend waiting on the network
+	Timeout time.Duration
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse Starlark checks from specs of the form "file=/etc/health-checker/replication.star,timeout=2s". The script is
+// read and compiled up front, so that a syntax error or an undefined name is reported when the health checker starts.
+func ParseStarlarkChecks(specs []string) ([]StarlarkCheck, error) {
+	rv := []StarlarkCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "file", "name", "max-steps", "timeout")
+		if err != nil {
+			return nil, err
+		}
+
+		path, err := spec.RequiredString("file")
+		if err != nil {
+			return nil, err
+		}
+
+		source, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, InvalidSpec{s, err.Error()}
+		}
+		program, err := CompileStarlark(path, source)
+		if err != nil {
+			return nil, InvalidSpec{s, err.Error()}
+		}
+
+		maxSteps, err := spec.Int("max-steps", 1000000)
+		if err != nil {
+			return nil, err
+		}
+		if maxSteps <= 0 {
+			return nil, spec.invalidValue("max-steps", "a positive integer")
+		}
+
+		timeout, err := spec.Duration("timeout", 5*time.Second)
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, StarlarkCheck{
+			Name:     spec.String("name", strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))),
+			Path:     path,
+			Program:  program,
+			MaxSteps: uint64(maxSteps),
+			Timeout:  timeout,
+			Negate:   negate,
+		})
+	}
+	return rv, nil
+}
+
+// Compile a script, resolving every name it uses against the Starlark language and our built-ins
+func CompileStarlark(path string, source []byte) (*starlark.Program, error) {
+	_, program, err := starlark.SourceProgram(path, source, func(name string) bool {
+		return containsString(StarlarkBuiltins, name)
+	})
+	return program, err
+}
@@ -0,0 +1,104 @@
+package options
+
+// A check that sends a command to a daemon that replies with lines of statistics, such as "STAT curr_connections 10",
+// and makes assertions about their values
+type StatsCheck struct {
+	// The protocol, which is only used to describe the check: memcached, or text for any other daemon
+	Protocol string
+	// The host:port of the daemon
+	Address string
+	// The command to send once the connection is open
+	Payload []byte
+	// If set, only lines that start with this prefix contain statistics, and any other line is an error
+	Prefix string
+	// The string that separates the name of a statistic from its value. If empty, they are separated by whitespace.
+	Separator string
+	// If set, the reply ends with a line equal to this. Otherwise, it ends when the daemon closes the connection.
+	End string
+	// The assertions about the statistics, which may refer to the rate of a counter with a _per_second suffix
+	Assertions []JsonAssertion
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse memcached checks from specs of the form
+// "address=127.0.0.1:11211,expect-stat=curr_connections < ${max_connections},warn-stat=evictions_per_second < 100"
+func ParseMemcachedChecks(specs []string) ([]StatsCheck, error) {
+	rv := []StatsCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "address", "expect-stat", "warn-stat")
+		if err != nil {
+			return nil, err
+		}
+
+		assertions, err := spec.JsonAssertions("expect-stat", "warn-stat")
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, StatsCheck{
+			Protocol:   "memcached",
+			Address:    spec.String("address", "127.0.0.1:11211"),
+			Payload:    []byte("stats\r\n"),
+			Prefix:     "STAT ",
+			End:        "END",
+			Assertions: assertions,
+			Negate:     negate,
+		})
+	}
+	return rv, nil
+}
+
+// Parse checks of daemons with a line based key/value protocol from specs of the form
+// "address=127.0.0.1:9999,send=show info\n,separator=:,expect-stat=CurrConns < ${Maxconn}", which reads the statistics
+// of HAProxy. HAProxy closes the connection after its reply. A daemon that keeps the connection open, waiting for the
+// next command, needs an "end" line, or the check times out.
+func ParseStatsChecks(specs []string) ([]StatsCheck, error) {
+	rv := []StatsCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "address", "send", "send-hex", "prefix", "separator", "end", "expect-stat", "warn-stat")
+		if err != nil {
+			return nil, err
+		}
+
+		address, err := spec.RequiredString("address")
+		if err != nil {
+			return nil, err
+		}
+
+		payload, err := spec.Payload("send")
+		if err != nil {
+			return nil, err
+		}
+
+		assertions, err := spec.JsonAssertions("expect-stat", "warn-stat")
+		if err != nil {
+			return nil, err
+		}
+		if len(assertions) == 0 {
+			return nil, InvalidSpec{s, "at least one of \"expect-stat\" and \"warn-stat\" is required"}
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, StatsCheck{
+			Protocol:   "text",
+			Address:    address,
+			Payload:    payload,
+			Prefix:     spec.String("prefix", ""),
+			Separator:  spec.String("separator", ""),
+			End:        spec.String("end", ""),
+			Assertions: assertions,
+			Negate:     negate,
+		})
+	}
+	return rv, nil
+}
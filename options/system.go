@@ -0,0 +1,147 @@
+package options
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The resources that Linux reports pressure stall information for
+var PressureResources = []string{"cpu", "memory", "io"}
+
+// The averaging windows that Linux reports pressure stall information for
+var PressureWindows = []string{"avg10", "avg60", "avg300"}
+
+// A check on the load average in /proc/loadavg
+type LoadCheck struct {
+	Load1  Thresholds
+	Load5  Thresholds
+	Load15 Thresholds
+	// If true, the load averages are divided by the number of CPUs before comparing them to the thresholds
+	PerCpu bool
+	// See Spec.Negate
+	Negate bool
+}
+
+// A check on the memory and swap usage in /proc/meminfo
+type MemoryCheck struct {
+	// The minimum percentage of memory that must be available (these are lower bounds)
+	AvailablePercent Thresholds
+	// The maximum percentage of swap that may be used
+	SwapUsedPercent Thresholds
+	// See Spec.Negate
+	Negate bool
+}
+
+// A check on the pressure stall information in /proc/pressure
+type PressureCheck struct {
+	// One of PressureResources
+	Resource string
+	// Either "some" or "full"
+	Kind string
+	// One of PressureWindows
+	Window string
+	// The maximum percentage of time that tasks may be stalled
+	Stalled Thresholds
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse load checks from specs of the form "warn-load1=4,fail-load1=8,per-cpu=true"
+func ParseLoadChecks(specs []string) ([]LoadCheck, error) {
+	rv := []LoadCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "warn-load1", "fail-load1", "warn-load5", "fail-load5", "warn-load15", "fail-load15", "per-cpu")
+		if err != nil {
+			return nil, err
+		}
+
+		check := LoadCheck{}
+		if check.Load1, err = spec.Thresholds("load1"); err != nil {
+			return nil, err
+		}
+		if check.Load5, err = spec.Thresholds("load5"); err != nil {
+			return nil, err
+		}
+		if check.Load15, err = spec.Thresholds("load15"); err != nil {
+			return nil, err
+		}
+		if check.PerCpu, err = spec.Bool("per-cpu", false); err != nil {
+			return nil, err
+		}
+
+		if check.Negate, err = spec.Negate(); err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, check)
+	}
+	return rv, nil
+}
+
+// Parse memory checks from specs of the form "warn-available-percent=10,fail-available-percent=5,fail-swap-used-percent=50"
+func ParseMemoryChecks(specs []string) ([]MemoryCheck, error) {
+	rv := []MemoryCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "warn-available-percent", "fail-available-percent", "warn-swap-used-percent", "fail-swap-used-percent")
+		if err != nil {
+			return nil, err
+		}
+
+		check := MemoryCheck{}
+		if check.AvailablePercent, err = spec.Thresholds("available-percent"); err != nil {
+			return nil, err
+		}
+		if check.SwapUsedPercent, err = spec.Thresholds("swap-used-percent"); err != nil {
+			return nil, err
+		}
+
+		if check.Negate, err = spec.Negate(); err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, check)
+	}
+	return rv, nil
+}
+
+// Parse pressure checks from specs of the form "resource=memory,kind=full,window=avg60,warn-stalled-percent=5,fail-stalled-percent=20"
+func ParsePressureChecks(specs []string) ([]PressureCheck, error) {
+	rv := []PressureCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "resource", "kind", "window", "warn-stalled-percent", "fail-stalled-percent")
+		if err != nil {
+			return nil, err
+		}
+
+		resource, err := spec.RequiredString("resource")
+		if err != nil {
+			return nil, err
+		}
+		if !containsString(PressureResources, resource) {
+			return nil, InvalidSpec{s, fmt.Sprintf("unknown resource \"%s\", must be one of: %s", resource, strings.Join(PressureResources, ", "))}
+		}
+
+		kind := spec.String("kind", "some")
+		if kind != "some" && kind != "full" {
+			return nil, InvalidSpec{s, fmt.Sprintf("unknown kind \"%s\", must be one of: some, full", kind)}
+		}
+
+		window := spec.String("window", "avg10")
+		if !containsString(PressureWindows, window) {
+			return nil, InvalidSpec{s, fmt.Sprintf("unknown window \"%s\", must be one of: %s", window, strings.Join(PressureWindows, ", "))}
+		}
+
+		stalled, err := spec.Thresholds("stalled-percent")
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, PressureCheck{Resource: resource, Kind: kind, Window: window, Stalled: stalled, Negate: negate})
+	}
+	return rv, nil
+}
@@ -0,0 +1,85 @@
+package options
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A check that opens a TCP connection to a port and optionally exchanges a payload, such as a PING, with the service
+// listening on it
+type TcpCheck struct {
+	// The port to connect to
+	Port int
+	// If set, this payload is sent once the connection is open
+	Payload []byte
+	// If set, the response must contain this string
+	Expect string
+	// If set, the response must match this pattern
+	ExpectRegex *regexp.Regexp
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse TCP checks from specs of the form "port=6379,send=PING\r\n,expect=+PONG"
+func ParseTcpChecks(specs []string) ([]TcpCheck, error) {
+	rv := []TcpCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "port", "send", "send-hex", "expect", "expect-regex")
+		if err != nil {
+			return nil, err
+		}
+
+		port, err := spec.Port("port")
+		if err != nil {
+			return nil, err
+		}
+
+		payload, err := spec.Payload("send")
+		if err != nil {
+			return nil, err
+		}
+
+		expectRegex, err := spec.Regexp("expect-regex")
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, TcpCheck{Port: port, Payload: payload, Expect: spec.String("expect", ""), ExpectRegex: expectRegex, Negate: negate})
+	}
+	return rv, nil
+}
+
+// Return true if the check waits for a response after connecting
+func (tcp TcpCheck) ExpectsResponse() bool {
+	return tcp.Expect != "" || tcp.ExpectRegex != nil
+}
+
+// Return true if the given response satisfies all the expectations of the check
+func (tcp TcpCheck) MatchesResponse(response []byte) bool {
+	if tcp.Expect != "" && !bytes.Contains(response, []byte(tcp.Expect)) {
+		return false
+	}
+	if tcp.ExpectRegex != nil && !tcp.ExpectRegex.Match(response) {
+		return false
+	}
+	return true
+}
+
+// Describe the expected response for use in error messages
+func (tcp TcpCheck) DescribeExpectedResponse() string {
+	descriptions := []string{}
+	if tcp.Expect != "" {
+		descriptions = append(descriptions, fmt.Sprintf("containing %q", tcp.Expect))
+	}
+	if tcp.ExpectRegex != nil {
+		descriptions = append(descriptions, fmt.Sprintf("matching %q", tcp.ExpectRegex))
+	}
+	return strings.Join(descriptions, " and ")
+}
@@ -0,0 +1,85 @@
+package options
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+)
+
+// A check that performs a TLS handshake and inspects the certificate the server presents
+type TlsCheck struct {
+	// The host:port to connect to
+	Address string
+	// The name to send via SNI and verify the certificate against. Defaults to the host in Address.
+	ServerName string
+	// The CAs to verify the certificate chain against. If nil, the system CAs are used.
+	RootCAs *x509.CertPool
+	// The check is degraded if the certificate expires within this many days
+	WarnDays int
+	// The check fails if the certificate expires within this many days
+	FailDays int
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse TLS checks from specs of the form "address=127.0.0.1:443,server-name=example.com,ca-file=/etc/ca.pem,warn-days=30,fail-days=7"
+func ParseTlsChecks(specs []string) ([]TlsCheck, error) {
+	rv := []TlsCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "address", "server-name", "ca-file", "warn-days", "fail-days")
+		if err != nil {
+			return nil, err
+		}
+
+		address, err := spec.RequiredString("address")
+		if err != nil {
+			return nil, err
+		}
+
+		serverName := spec.String("server-name", "")
+		if serverName == "" {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, InvalidSpec{s, "the value of \"address\" must be of the form host:port"}
+			}
+			serverName = host
+		}
+
+		var rootCAs *x509.CertPool
+		if spec.Has("ca-file") {
+			pem, err := ioutil.ReadFile(spec.String("ca-file", ""))
+			if err != nil {
+				return nil, InvalidSpec{s, err.Error()}
+			}
+			rootCAs = x509.NewCertPool()
+			if !rootCAs.AppendCertsFromPEM(pem) {
+				return nil, InvalidSpec{s, "no PEM encoded certificates found in the ca-file"}
+			}
+		}
+
+		warnDays, err := spec.Int("warn-days", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		failDays, err := spec.Int("fail-days", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, TlsCheck{
+			Address:    address,
+			ServerName: serverName,
+			RootCAs:    rootCAs,
+			WarnDays:   warnDays,
+			FailDays:   failDays,
+			Negate:     negate,
+		})
+	}
+	return rv, nil
+}
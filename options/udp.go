@@ -0,0 +1,84 @@
+package options
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A check that sends a datagram to a UDP port and optionally waits for a response
+type UdpCheck struct {
+	// The host:port to send the payload to
+	Address string
+	// The payload to send
+	Payload []byte
+	// If set, a response containing this string must be received
+	Expect string
+	// If set, a response matching this pattern must be received
+	ExpectRegex *regexp.Regexp
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse UDP checks from specs of the form "address=127.0.0.1:8125,send=health:1|c,expect=..."
+func ParseUdpChecks(specs []string) ([]UdpCheck, error) {
+	rv := []UdpCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "address", "send", "send-hex", "expect", "expect-regex")
+		if err != nil {
+			return nil, err
+		}
+
+		address, err := spec.RequiredString("address")
+		if err != nil {
+			return nil, err
+		}
+
+		payload, err := spec.Payload("send")
+		if err != nil {
+			return nil, err
+		}
+
+		expectRegex, err := spec.Regexp("expect-regex")
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, UdpCheck{Address: address, Payload: payload, Expect: spec.String("expect", ""), ExpectRegex: expectRegex, Negate: negate})
+	}
+	return rv, nil
+}
+
+// Return true if the check waits for a response after sending its payload
+func (udp UdpCheck) ExpectsResponse() bool {
+	return udp.Expect != "" || udp.ExpectRegex != nil
+}
+
+// Return true if the given response satisfies all the expectations of the check
+func (udp UdpCheck) MatchesResponse(response []byte) bool {
+	if udp.Expect != "" && !bytes.Contains(response, []byte(udp.Expect)) {
+		return false
+	}
+	if udp.ExpectRegex != nil && !udp.ExpectRegex.Match(response) {
+		return false
+	}
+	return true
+}
+
+// Describe the expected response for use in error messages
+func (udp UdpCheck) DescribeExpectedResponse() string {
+	descriptions := []string{}
+	if udp.Expect != "" {
+		descriptions = append(descriptions, fmt.Sprintf("containing %q", udp.Expect))
+	}
+	if udp.ExpectRegex != nil {
+		descriptions = append(descriptions, fmt.Sprintf("matching %q", udp.ExpectRegex))
+	}
+	return strings.Join(descriptions, " and ")
+}
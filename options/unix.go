@@ -0,0 +1,33 @@
+package options
+
+// A check that connects to a Unix domain socket
+type UnixCheck struct {
+	// The path of the socket. A path starting with @ is a socket in the abstract namespace.
+	Path string
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse Unix socket checks from specs of the form "path=/var/run/docker.sock" or "path=@/containerd-shim/k8s.sock"
+func ParseUnixChecks(specs []string) ([]UnixCheck, error) {
+	rv := []UnixCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "path")
+		if err != nil {
+			return nil, err
+		}
+
+		path, err := spec.RequiredString("path")
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, UnixCheck{Path: path, Negate: negate})
+	}
+	return rv, nil
+}
@@ -0,0 +1,110 @@
+package options
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// A check that performs the WebSocket upgrade handshake, optionally exchanges a message with the server, and then
+// closes the connection
+type WebsocketCheck struct {
+	// The ws:// or wss:// URL to connect to
+	Url string
+	// If set, the Origin header to send with the handshake, for servers that only accept connections from some origins
+	Origin string
+	// If set, this message is sent once the handshake is complete
+	Payload []byte
+	// If true, the payload is sent as a binary message rather than a text message, because it was given as hex
+	Binary bool
+	// If set, the first message from the server must contain this string
+	Expect string
+	// If set, the first message from the server must match this pattern
+	ExpectRegex *regexp.Regexp
+	// How long to wait for the whole exchange, including closing the connection
+	Timeout time.Duration
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse WebSocket checks from specs of the form "url=ws://127.0.0.1:8080/socket,send={"type":"ping"},expect=pong"
+func ParseWebsocketChecks(specs []string) ([]WebsocketCheck, error) {
+	rv := []WebsocketCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "url", "origin", "send", "send-hex", "expect", "expect-regex", "timeout")
+		if err != nil {
+			return nil, err
+		}
+
+		rawUrl, err := spec.RequiredString("url")
+		if err != nil {
+			return nil, err
+		}
+		if u, err := url.Parse(rawUrl); err != nil || (u.Scheme != "ws" && u.Scheme != "wss") {
+			return nil, spec.invalidValue("url", "a ws:// or wss:// URL")
+		}
+
+		payload, err := spec.Payload("send")
+		if err != nil {
+			return nil, err
+		}
+
+		expectRegex, err := spec.Regexp("expect-regex")
+		if err != nil {
+			return nil, err
+		}
+
+		timeout, err := spec.Duration("timeout", 5*time.Second)
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, WebsocketCheck{
+			Url:         rawUrl,
+			Origin:      spec.String("origin", ""),
+			Payload:     payload,
+			Binary:      spec.Has("send-hex"),
+			Expect:      spec.String("expect", ""),
+			ExpectRegex: expectRegex,
+			Timeout:     timeout,
+			Negate:      negate,
+		})
+	}
+	return rv, nil
+}
+
+// Return true if the check waits for a message from the server after the handshake
+func (websocket WebsocketCheck) ExpectsReply() bool {
+	return websocket.Expect != "" || websocket.ExpectRegex != nil
+}
+
+// Return true if the given message satisfies all the expectations of the check
+func (websocket WebsocketCheck) MatchesReply(message []byte) bool {
+	if websocket.Expect != "" && !bytes.Contains(message, []byte(websocket.Expect)) {
+		return false
+	}
+	if websocket.ExpectRegex != nil && !websocket.ExpectRegex.Match(message) {
+		return false
+	}
+	return true
+}
+
+// Describe the expected message for use in error messages
+func (websocket WebsocketCheck) DescribeExpectedReply() string {
+	descriptions := []string{}
+	if websocket.Expect != "" {
+		descriptions = append(descriptions, fmt.Sprintf("containing %q", websocket.Expect))
+	}
+	if websocket.ExpectRegex != nil {
+		descriptions = append(descriptions, fmt.Sprintf("matching %q", websocket.ExpectRegex))
+	}
+	return strings.Join(descriptions, " and ")
+}
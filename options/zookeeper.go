@@ -0,0 +1,59 @@
+package options
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The values of zk_server_state that a ZooKeeper check can require
+var ZookeeperServerStates = []string{"leader", "follower", "observer", "standalone"}
+
+// A check that talks to ZooKeeper using its four letter word commands
+type ZookeeperCheck struct {
+	// The host:port of the ZooKeeper client port
+	Address string
+	// If set, zk_server_state must be one of these
+	States []string
+	// If greater than zero and the server is the leader, zk_synced_followers must be at least this
+	MinSyncedFollowers int
+	// See Spec.Negate
+	Negate bool
+}
+
+// Parse ZooKeeper checks from specs of the form "address=127.0.0.1:2181,state=leader,state=follower,min-synced-followers=2"
+func ParseZookeeperChecks(specs []string) ([]ZookeeperCheck, error) {
+	rv := []ZookeeperCheck{}
+	for _, s := range specs {
+		spec, err := ParseSpec(s, "address", "state", "min-synced-followers")
+		if err != nil {
+			return nil, err
+		}
+
+		states := []string{}
+		for _, state := range spec.Strings("state") {
+			state = strings.ToLower(state)
+			if !containsString(ZookeeperServerStates, state) {
+				return nil, InvalidSpec{s, fmt.Sprintf("unknown state \"%s\", must be one of: %s", state, strings.Join(ZookeeperServerStates, ", "))}
+			}
+			states = append(states, state)
+		}
+
+		minSyncedFollowers, err := spec.Int("min-synced-followers", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		negate, err := spec.Negate()
+		if err != nil {
+			return nil, err
+		}
+
+		rv = append(rv, ZookeeperCheck{
+			Address:            spec.String("address", "127.0.0.1:2181"),
+			States:             states,
+			MinSyncedFollowers: minSyncedFollowers,
+			Negate:             negate,
+		})
+	}
+	return rv, nil
+}
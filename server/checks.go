@@ -0,0 +1,298 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// How long a check that talks to the network waits before giving up
+const defaultCheckTimeout = time.Second * 5
+
+// A single health check, such as a TCP connection to a port or the execution of a script
+type check interface {
+	// A short description of the check, used in log output
+	Name() string
+	// Run the check once and report the outcome
+	Run() *checkResult
+}
+
+// The outcome of running a single check. A nil Err means the check passed. A check that passed can still be degraded,
+// in which case Warning explains why. Details holds any values the check measured, such as a certificate's expiry.
+type checkResult struct {
+	Name    string
+	Err     error
+	Warning error
+	Details map[string]string
+}
+
+func (result *checkResult) Passed() bool {
+	return result.Err == nil
+}
+
+func (result *checkResult) Degraded() bool {
+	return result.Err == nil && result.Warning != nil
+}
+
+// Format the details of the result as a sorted list of key=value pairs, for use in log output
+func (result *checkResult) formatDetails() string {
+	keys := []string{}
+	for key := range result.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", key, result.Details[key]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// Build the list of checks configured in opts
+func buildChecks(opts *options.Options) []check {
+	checks := []check{}
+
+	for _, port := range opts.Ports {
+		checks = append(checks, &tcpCheck{tcp: options.TcpCheck{Port: port}, opts: opts})
+	}
+
+	for _, tcp := range opts.TcpChecks {
+		checks = append(checks, negateIf(tcp.Negate, &tcpCheck{tcp: tcp, opts: opts}, opts))
+	}
+
+	for _, script := range opts.Scripts {
+		checks = append(checks, negateIf(script.Negate, &scriptCheck{script: script, opts: opts}, opts))
+	}
+
+	for _, dns := range opts.DnsChecks {
+		checks = append(checks, negateIf(dns.Negate, &dnsCheck{dns: dns, opts: opts}, opts))
+	}
+
+	for _, udp := range opts.UdpChecks {
+		checks = append(checks, negateIf(udp.Negate, &udpCheck{udp: udp, opts: opts}, opts))
+	}
+
+	for _, zookeeper := range opts.ZookeeperChecks {
+		checks = append(checks, negateIf(zookeeper.Negate, &zookeeperCheck{zookeeper: zookeeper, opts: opts}, opts))
+	}
+
+	for _, tls := range opts.TlsChecks {
+		checks = append(checks, negateIf(tls.Negate, &tlsCheck{tls: tls, opts: opts}, opts))
+	}
+
+	for _, disk := range opts.DiskChecks {
+		checks = append(checks, negateIf(disk.Negate, &diskCheck{disk: disk, opts: opts}, opts))
+	}
+
+	for _, load := range opts.LoadChecks {
+		checks = append(checks, negateIf(load.Negate, &loadCheck{load: load, opts: opts}, opts))
+	}
+
+	for _, memory := range opts.MemoryChecks {
+		checks = append(checks, negateIf(memory.Negate, &memoryCheck{memory: memory, opts: opts}, opts))
+	}
+
+	for _, pressure := range opts.PressureChecks {
+		checks = append(checks, negateIf(pressure.Negate, &pressureCheck{pressure: pressure, opts: opts}, opts))
+	}
+
+	for _, process := range opts.ProcessChecks {
+		checks = append(checks, negateIf(process.Negate, &processCheck{process: process, opts: opts}, opts))
+	}
+
+	for _, socketOwner := range opts.SocketOwnerChecks {
+		checks = append(checks, negateIf(socketOwner.Negate, &socketOwnerCheck{socketOwner: socketOwner, opts: opts}, opts))
+	}
+
+	for _, quorum := range opts.QuorumChecks {
+		checks = append(checks, negateIf(quorum.Negate, &quorumCheck{quorum: quorum, opts: opts}, opts))
+	}
+
+	for _, file := range opts.FileChecks {
+		checks = append(checks, negateIf(file.Negate, &fileCheck{file: file, opts: opts}, opts))
+	}
+
+	for _, logPattern := range opts.LogPatternChecks {
+		checks = append(checks, negateIf(logPattern.Negate, &logPatternCheck{logPattern: logPattern, opts: opts}, opts))
+	}
+
+	for _, http := range opts.HttpChecks {
+		checks = append(checks, negateIf(http.Negate, &httpCheck{http: http, opts: opts}, opts))
+	}
+
+	for _, scenario := range opts.ScenarioChecks {
+		checks = append(checks, negateIf(scenario.Negate, &scenarioCheck{scenario: scenario, opts: opts}, opts))
+	}
+
+	for _, unix := range opts.UnixChecks {
+		checks = append(checks, negateIf(unix.Negate, &unixCheck{unix: unix, opts: opts}, opts))
+	}
+
+	for _, docker := range opts.DockerChecks {
+		checks = append(checks, negateIf(docker.Negate, &dockerCheck{docker: docker, opts: opts}, opts))
+	}
+
+	for _, redis := range opts.RedisChecks {
+		checks = append(checks, negateIf(redis.Negate, &redisCheck{redis: redis, opts: opts}, opts))
+	}
+
+	for _, postgres := range opts.PostgresChecks {
+		checks = append(checks, negateIf(postgres.Negate, &postgresCheck{postgres: postgres, opts: opts}, opts))
+	}
+
+	for _, mysql := range opts.MysqlChecks {
+		checks = append(checks, negateIf(mysql.Negate, &mysqlCheck{mysql: mysql, opts: opts}, opts))
+	}
+
+	for i, memcached := range opts.MemcachedChecks {
+		checks = append(checks, negateIf(memcached.Negate, &statsCheck{stats: memcached, index: i, opts: opts}, opts))
+	}
+
+	for i, stats := range opts.StatsChecks {
+		checks = append(checks, negateIf(stats.Negate, &statsCheck{stats: stats, index: i, opts: opts}, opts))
+	}
+
+	for _, prometheus := range opts.PrometheusChecks {
+		checks = append(checks, negateIf(prometheus.Negate, &prometheusCheck{prometheus: prometheus, opts: opts}, opts))
+	}
+
+	for _, ping := range opts.PingChecks {
+		checks = append(checks, negateIf(ping.Negate, &pingCheck{ping: ping, opts: opts}, opts))
+	}
+
+	for _, websocket := range opts.WebsocketChecks {
+		checks = append(checks, negateIf(websocket.Negate, &websocketCheck{websocket: websocket, opts: opts}, opts))
+	}
+
+	for _, starlark := range opts.StarlarkChecks {
+		checks = append(checks, negateIf(starlark.Negate, &starlarkCheck{starlark: starlark, opts: opts}, opts))
+	}
+
+	return checks
+}
+
+// Run all the configured checks concurrently and return their results in the order the checks were configured
+func performChecks(opts *options.Options) []*checkResult {
+	logger := opts.Logger
+	checks := buildChecks(opts)
+	results := make([]*checkResult, len(checks))
+
+	var waitGroup = sync.WaitGroup{}
+
+	for i, c := range checks {
+		waitGroup.Add(1)
+		go func(i int, c check) {
+			defer waitGroup.Done()
+
+			result := c.Run()
+			result.Name = c.Name()
+			switch {
+			case result.Degraded():
+				logger.Warnf("%s DEGRADED: %s", result.Name, result.Warning)
+			case result.Passed():
+				logger.Infof("%s successful", result.Name)
+			default:
+				logger.Warnf("%s FAILED: %s", result.Name, result.Err)
+			}
+			if len(result.Details) > 0 {
+				logger.Infof("%s details: %s", result.Name, result.formatDetails())
+			}
+
+			results[i] = result
+		}(i, c)
+	}
+
+	waitGroup.Wait()
+
+	return results
+}
+
+// Count how many of the given results are degraded
+func countDegraded(results []*checkResult) int {
+	degraded := 0
+	for _, result := range results {
+		if result.Degraded() {
+			degraded++
+		}
+	}
+	return degraded
+}
+
+// Count how many of the given results passed
+func countPassed(results []*checkResult) int {
+	passed := 0
+	for _, result := range results {
+		if result.Passed() {
+			passed++
+		}
+	}
+	return passed
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Check that we can open a TCP connection to a port, and optionally that it responds as expected
+type tcpCheck struct {
+	tcp  options.TcpCheck
+	opts *options.Options
+}
+
+func (c *tcpCheck) Name() string {
+	return fmt.Sprintf("TCP connection to port %d", c.tcp.Port)
+}
+
+func (c *tcpCheck) Run() *checkResult {
+	return &checkResult{Err: attemptTcpConnection(c.tcp, c.opts)}
+}
+
+func (c *tcpCheck) negatedError() error {
+	return PortUnexpectedlyOpen(c.tcp.Port)
+}
+
+// Check that a script exits with a zero exit status within the configured timeout
+type scriptCheck struct {
+	script options.Script
+	opts   *options.Options
+}
+
+func (c *scriptCheck) Name() string {
+	return fmt.Sprintf("Script %v", c.script.Name)
+}
+
+func (c *scriptCheck) Run() *checkResult {
+	logger := c.opts.Logger
+	logger.Infof("Executing '%v' with a timeout of %v seconds...", c.script, c.opts.ScriptTimeout)
+
+	timeout := time.Second * time.Duration(c.opts.ScriptTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.script.Name, c.script.Args...)
+
+	output, err := cmd.Output()
+	if err != nil {
+		logger.Warnf("Command output: %s", output)
+	}
+
+	return &checkResult{Err: err}
+}
+
+func (c *scriptCheck) negatedError() error {
+	return ScriptUnexpectedlySucceeded(c.script.Name)
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// The space and inodes used by a filesystem
+type diskUsage struct {
+	TotalBytes  uint64
+	FreeBytes   uint64
+	UsedPercent float64
+	TotalInodes uint64
+	FreeInodes  uint64
+}
+
+// Check that the filesystem at a path has enough free space and inodes
+type diskCheck struct {
+	disk options.DiskCheck
+	opts *options.Options
+}
+
+func (c *diskCheck) Name() string {
+	return fmt.Sprintf("Disk usage of %s", c.disk.Path)
+}
+
+func (c *diskCheck) Run() *checkResult {
+	c.opts.Logger.Infof("Attempting to read disk usage of %s...", c.disk.Path)
+
+	usage, err := getDiskUsage(c.disk.Path)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	return evaluateDiskUsage(c.disk, usage)
+}
+
+// Compare the usage of a filesystem to the fail and warn thresholds in the given check
+func evaluateDiskUsage(disk options.DiskCheck, usage diskUsage) *checkResult {
+	result := &checkResult{}
+	result.checkMaximum("used_percent", usage.UsedPercent, disk.UsedPercent)
+	result.checkMinimum("free_bytes", float64(usage.FreeBytes), disk.FreeBytes)
+	result.checkMinimum("free_inodes", float64(usage.FreeInodes), disk.FreeInodes)
+
+	// Counts are reported without the decimals that checkMinimum gives them
+	result.Details["free_bytes"] = strconv.FormatUint(usage.FreeBytes, 10)
+	result.Details["free_inodes"] = strconv.FormatUint(usage.FreeInodes, 10)
+	result.Details["total_bytes"] = strconv.FormatUint(usage.TotalBytes, 10)
+	result.Details["total_inodes"] = strconv.FormatUint(usage.TotalInodes, 10)
+	return result
+}
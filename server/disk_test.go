@@ -0,0 +1,88 @@
+package server
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateDiskUsage(t *testing.T) {
+	t.Parallel()
+
+	usage := diskUsage{TotalBytes: 100 << 30, FreeBytes: 10 << 30, UsedPercent: 90, TotalInodes: 1000000, FreeInodes: 5000}
+
+	testCases := []struct {
+		name            string
+		disk            options.DiskCheck
+		expectedErr     string
+		expectedWarning string
+	}{
+		{
+			"no thresholds",
+			options.DiskCheck{},
+			"",
+			"",
+		},
+		{
+			"within thresholds",
+			options.DiskCheck{UsedPercent: options.Thresholds{Warn: 95, Fail: 99}, FreeBytes: options.Thresholds{Warn: 5 << 30}, FreeInodes: options.Thresholds{Fail: 1000}},
+			"",
+			"",
+		},
+		{
+			"used percent warning",
+			options.DiskCheck{UsedPercent: options.Thresholds{Warn: 80, Fail: 95}},
+			"",
+			"used_percent is 90.00, which is more than 80.00",
+		},
+		{
+			"used percent failure",
+			options.DiskCheck{UsedPercent: options.Thresholds{Warn: 80, Fail: 85}},
+			"used_percent is 90.00, which is more than 85.00",
+			"used_percent is 90.00, which is more than 80.00",
+		},
+		{
+			"free bytes failure",
+			options.DiskCheck{FreeBytes: options.Thresholds{Fail: 20 << 30}},
+			"free_bytes is 10737418240.00, which is less than 21474836480.00",
+			"",
+		},
+		{
+			"free inodes warning",
+			options.DiskCheck{FreeInodes: options.Thresholds{Warn: 10000}},
+			"",
+			"free_inodes is 5000.00, which is less than 10000.00",
+		},
+	}
+
+	for _, testCase := range testCases {
+		result := evaluateDiskUsage(testCase.disk, usage)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Equal(t, testCase.expectedErr, result.Err.Error(), testCase.name)
+		}
+		if testCase.expectedWarning == "" {
+			assert.Nil(t, result.Warning, testCase.name)
+		} else if assert.NotNil(t, result.Warning, testCase.name) {
+			assert.Equal(t, testCase.expectedWarning, result.Warning.Error(), testCase.name)
+		}
+		assert.Equal(t, "90.00", result.Details["used_percent"], testCase.name)
+		assert.Equal(t, "5000", result.Details["free_inodes"], testCase.name)
+	}
+}
+
+func TestGetDiskUsage(t *testing.T) {
+	t.Parallel()
+
+	usage, err := getDiskUsage(os.TempDir())
+	if assert.Nil(t, err) {
+		assert.True(t, usage.TotalBytes > 0, "Expected total bytes to be more than zero")
+		assert.True(t, usage.UsedPercent >= 0 && usage.UsedPercent <= 100, "Expected used percent to be between 0 and 100, but got %f", usage.UsedPercent)
+	}
+
+	_, err = getDiskUsage("/this/path/does/not/exist")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+// +build !windows
+
+package server
+
+import (
+	"syscall"
+)
+
+// Use statfs to find the space and inodes used by the filesystem that contains path
+func getDiskUsage(path string) (diskUsage, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return diskUsage{}, err
+	}
+
+	blockSize := uint64(stat.Bsize)
+	usedBlocks := uint64(stat.Blocks) - uint64(stat.Bfree)
+
+	// Like df, compute the percentage used relative to the space available to unprivileged users, which excludes the
+	// blocks reserved for root
+	usedPercent := 0.0
+	if usedBlocks+uint64(stat.Bavail) > 0 {
+		usedPercent = float64(usedBlocks) * 100 / float64(usedBlocks+uint64(stat.Bavail))
+	}
+
+	return diskUsage{
+		TotalBytes:  uint64(stat.Blocks) * blockSize,
+		FreeBytes:   uint64(stat.Bavail) * blockSize,
+		UsedPercent: usedPercent,
+		TotalInodes: uint64(stat.Files),
+		FreeInodes:  uint64(stat.Ffree),
+	}, nil
+}
@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package server
+
+import (
+	"errors"
+)
+
+func getDiskUsage(path string) (diskUsage, error) {
+	return diskUsage{}, errors.New("disk checks are not supported on Windows")
+}
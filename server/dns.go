@@ -0,0 +1,442 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// The codes of the record types we support, and the other values we need from RFC 1035
+var dnsRecordTypeCodes = map[string]uint16{
+	"A":     dnsTypeA,
+	"AAAA":  dnsTypeAAAA,
+	"CNAME": dnsTypeCNAME,
+	"MX":    dnsTypeMX,
+	"NS":    dnsTypeNS,
+	"TXT":   dnsTypeTXT,
+}
+
+const (
+	dnsTypeA     = 1
+	dnsTypeNS    = 2
+	dnsTypeCNAME = 5
+	dnsTypeMX    = 15
+	dnsTypeTXT   = 16
+	dnsTypeAAAA  = 28
+
+	dnsClassInternet    = 1
+	dnsRecursionDesired = 0x0100
+	dnsTruncated        = 0x0200
+	dnsNameError        = 3
+)
+
+// Returned by parseDnsReply when the server says that the name doesn't exist
+var errDnsNameNotFound = errors.New("no such host")
+
+// Check that a name resolves via DNS, optionally to a set of expected answers and within a maximum latency
+type dnsCheck struct {
+	dns  options.DnsCheck
+	opts *options.Options
+}
+
+func (c *dnsCheck) Name() string {
+	server := c.dns.Server
+	if server == "" {
+		server = "the system resolver"
+	}
+	return fmt.Sprintf("DNS %s lookup of %s via %s", c.dns.RecordType, c.dns.Name, server)
+}
+
+func (c *dnsCheck) Run() *checkResult {
+	return &checkResult{Err: attemptDnsLookup(c.dns, c.opts)}
+}
+
+// Resolve the name in the given check and verify the answers and latency
+func attemptDnsLookup(dns options.DnsCheck, opts *options.Options) error {
+	logger := opts.Logger
+	logger.Infof("Attempting to resolve %s record for %s...", dns.RecordType, dns.Name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	var answers []string
+	var err error
+	if dns.Server != "" {
+		answers, err = queryDnsServer(ctx, dns.Server, dns.RecordType, dns.Name)
+	} else {
+		answers, err = lookupDnsRecords(ctx, net.DefaultResolver, dns.RecordType, dns.Name)
+	}
+	latency := time.Since(start)
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("DNS lookup of %s returned %v in %s", dns.Name, answers, latency)
+
+	if len(answers) == 0 {
+		return NoDnsAnswers(dns.Name)
+	}
+
+	for _, expected := range dns.ExpectedAnswers {
+		if !containsDnsAnswer(answers, expected) {
+			return MissingDnsAnswer{expected: expected, actual: answers}
+		}
+	}
+
+	if dns.MaxLatency > 0 && latency > dns.MaxLatency {
+		return SlowDnsLookup{latency: latency, maxLatency: dns.MaxLatency}
+	}
+
+	return nil
+}
+
+// Look up records of the given type with the system resolver, returning the answers as strings
+func lookupDnsRecords(ctx context.Context, resolver *net.Resolver, recordType string, name string) ([]string, error) {
+	answers := []string{}
+
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		// The resolver returns the name itself when it has no CNAME record
+		if !strings.EqualFold(strings.TrimSuffix(cname, "."), strings.TrimSuffix(name, ".")) {
+			answers = append(answers, cname)
+		}
+	case "MX":
+		records, err := resolver.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			answers = append(answers, record.Host)
+		}
+	case "NS":
+		records, err := resolver.LookupNS(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			answers = append(answers, record.Host)
+		}
+	case "TXT":
+		records, err := resolver.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, records...)
+	default:
+		return nil, fmt.Errorf("unsupported DNS record type %s", recordType)
+	}
+
+	sort.Strings(answers)
+	return answers, nil
+}
+
+// Send a query for records of the given type straight to server, and return the answers as strings. We don't use a
+// net.Resolver for this, since the Go resolver answers names in /etc/hosts without asking the server at all.
+func queryDnsServer(ctx context.Context, server string, recordType string, name string) ([]string, error) {
+	queryType, ok := dnsRecordTypeCodes[recordType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported DNS record type %s", recordType)
+	}
+
+	// The ID is all that stops a spoofed reply from being accepted, so make it unpredictable
+	idBytes := make([]byte, 2)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	id := binary.BigEndian.Uint16(idBytes)
+	query, err := newDnsQuery(id, name, queryType)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := exchangeDnsMessage(ctx, "udp", server, id, query)
+	if err != nil {
+		return nil, err
+	}
+	// The reply didn't fit in a UDP packet, so ask again over TCP
+	if binary.BigEndian.Uint16(reply[2:4])&dnsTruncated != 0 {
+		if reply, err = exchangeDnsMessage(ctx, "tcp", server, id, query); err != nil {
+			return nil, err
+		}
+	}
+
+	answers, err := parseDnsReply(reply, queryType)
+	if err == errDnsNameNotFound {
+		return nil, &net.DNSError{Err: "no such host", Name: name, Server: server, IsNotFound: true}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(answers)
+	return answers, nil
+}
+
+// Build a query for the given name and type, asking the server to recurse
+func newDnsQuery(id uint16, name string, queryType uint16) ([]byte, error) {
+	query := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(query[0:], id)
+	binary.BigEndian.PutUint16(query[2:], dnsRecursionDesired)
+	binary.BigEndian.PutUint16(query[4:], 1)
+
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, InvalidDnsName(name)
+		}
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+	query = append(query, 0, byte(queryType>>8), byte(queryType), 0, dnsClassInternet)
+
+	return query, nil
+}
+
+// Send a query to the server and return its reply, ignoring any stray replies to other queries over UDP. Over TCP, each
+// message is preceded by its length.
+func exchangeDnsMessage(ctx context.Context, network string, server string, id uint16, query []byte) ([]byte, error) {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "tcp" {
+		length := []byte{byte(len(query) >> 8), byte(len(query))}
+		if _, err := conn.Write(append(length, query...)); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return nil, err
+		}
+		reply := make([]byte, binary.BigEndian.Uint16(length))
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return nil, err
+		}
+		if len(reply) < 12 || binary.BigEndian.Uint16(reply) != id {
+			return nil, InvalidDnsReply("the reply does not match the query")
+		}
+		return reply, nil
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		if n >= 12 && binary.BigEndian.Uint16(buf) == id {
+			return buf[:n], nil
+		}
+	}
+}
+
+// Return the answers of the given type in a reply. Names are returned fully qualified, like the Go resolver does.
+func parseDnsReply(reply []byte, queryType uint16) ([]string, error) {
+	if len(reply) < 12 {
+		return nil, InvalidDnsReply("the header is truncated")
+	}
+	flags := binary.BigEndian.Uint16(reply[2:4])
+	switch rcode := flags & 0xf; rcode {
+	case 0:
+	case dnsNameError:
+		return nil, errDnsNameNotFound
+	default:
+		return nil, DnsServerError(rcode)
+	}
+
+	questions := int(binary.BigEndian.Uint16(reply[4:6]))
+	records := int(binary.BigEndian.Uint16(reply[6:8]))
+	offset := 12
+
+	for i := 0; i < questions; i++ {
+		_, next, err := readDnsName(reply, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = next + 4
+	}
+
+	answers := []string{}
+	for i := 0; i < records; i++ {
+		_, next, err := readDnsName(reply, offset)
+		if err != nil {
+			return nil, err
+		}
+		if next+10 > len(reply) {
+			return nil, InvalidDnsReply("an answer is truncated")
+		}
+		recordType := binary.BigEndian.Uint16(reply[next:])
+		length := int(binary.BigEndian.Uint16(reply[next+8:]))
+		start := next + 10
+		offset = start + length
+		if offset > len(reply) {
+			return nil, InvalidDnsReply("an answer is truncated")
+		}
+		if recordType != queryType {
+			// For example, the CNAME records that lead to the A records we asked for
+			continue
+		}
+
+		data := reply[start:offset]
+		switch recordType {
+		case dnsTypeA, dnsTypeAAAA:
+			if (recordType == dnsTypeA && len(data) != net.IPv4len) || (recordType == dnsTypeAAAA && len(data) != net.IPv6len) {
+				return nil, InvalidDnsReply("an address has the wrong length")
+			}
+			answers = append(answers, net.IP(data).String())
+		case dnsTypeCNAME, dnsTypeNS, dnsTypeMX:
+			// An MX record starts with a 2 byte preference
+			if recordType == dnsTypeMX {
+				start += 2
+			}
+			name, _, err := readDnsName(reply, start)
+			if err != nil {
+				return nil, err
+			}
+			answers = append(answers, name)
+		case dnsTypeTXT:
+			// A TXT record is a series of strings, each preceded by its length, which make up a single answer
+			text := strings.Builder{}
+			for len(data) > 0 {
+				if 1+int(data[0]) > len(data) {
+					return nil, InvalidDnsReply("a TXT record is truncated")
+				}
+				text.Write(data[1 : 1+int(data[0])])
+				data = data[1+int(data[0]):]
+			}
+			answers = append(answers, text.String())
+		}
+	}
+
+	return answers, nil
+}
+
+// Read the possibly compressed name at offset, and return it along with the offset just past it
+func readDnsName(message []byte, offset int) (string, int, error) {
+	labels := []string{}
+	end := -1
+
+	// Each pointer must point backwards, so there can't be more of them than bytes in the message
+	for jumps := 0; jumps < len(message); jumps++ {
+		if offset >= len(message) {
+			return "", 0, InvalidDnsReply("a name is truncated")
+		}
+		length := int(message[offset])
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+		case length&0xc0 == 0xc0:
+			if offset+2 > len(message) {
+				return "", 0, InvalidDnsReply("a name is truncated")
+			}
+			if end < 0 {
+				end = offset + 2
+			}
+			pointer := int(binary.BigEndian.Uint16(message[offset:]) & 0x3fff)
+			if pointer >= offset {
+				return "", 0, InvalidDnsReply("a name has an invalid pointer")
+			}
+			offset = pointer
+		default:
+			if offset+1+length > len(message) {
+				return "", 0, InvalidDnsReply("a name is truncated")
+			}
+			labels = append(labels, string(message[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+
+	return "", 0, InvalidDnsReply("a name has too many pointers")
+}
+
+// Names in answers are fully qualified (e.g. "example.com."), so compare them without the trailing dot. DNS names are
+// case insensitive, and servers may answer with a different case than the one we expect.
+func containsDnsAnswer(answers []string, expected string) bool {
+	for _, answer := range answers {
+		if strings.EqualFold(strings.TrimSuffix(answer, "."), strings.TrimSuffix(expected, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Custom error types
+
+type NoDnsAnswers string
+
+func (name NoDnsAnswers) Error() string {
+	return fmt.Sprintf("DNS lookup of %s returned no answers", string(name))
+}
+
+type MissingDnsAnswer struct {
+	expected string
+	actual   []string
+}
+
+func (err MissingDnsAnswer) Error() string {
+	return fmt.Sprintf("expected answer %s was not found in %v", err.expected, err.actual)
+}
+
+type SlowDnsLookup struct {
+	latency    time.Duration
+	maxLatency time.Duration
+}
+
+func (err SlowDnsLookup) Error() string {
+	return fmt.Sprintf("DNS lookup took %s, which is more than the maximum of %s", err.latency, err.maxLatency)
+}
+
+type InvalidDnsName string
+
+func (name InvalidDnsName) Error() string {
+	return fmt.Sprintf("%s is not a valid DNS name", string(name))
+}
+
+type InvalidDnsReply string
+
+func (reason InvalidDnsReply) Error() string {
+	return fmt.Sprintf("invalid DNS reply: %s", string(reason))
+}
+
+type DnsServerError uint16
+
+func (rcode DnsServerError) Error() string {
+	return fmt.Sprintf("DNS server replied with error code %d", uint16(rcode))
+}
@@ -0,0 +1,459 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptDnsLookup(t *testing.T) {
+	t.Parallel()
+
+	server := startFakeDnsServer(t, map[string][]string{
+		"db.health-checker.test.":  {"10.0.0.1", "10.0.0.2"},
+		"www.health-checker.test.": {"db.health-checker.test."},
+	}, 0)
+
+	slowServer := startFakeDnsServer(t, map[string][]string{
+		"db.health-checker.test.": {"10.0.0.1"},
+	}, 200*time.Millisecond)
+
+	testCases := []struct {
+		name        string
+		dns         options.DnsCheck
+		expectedErr string
+	}{
+		{
+			"resolves",
+			options.DnsCheck{Name: "db.health-checker.test", Server: server, RecordType: "A"},
+			"",
+		},
+		{
+			"expected answers present",
+			options.DnsCheck{Name: "db.health-checker.test", Server: server, RecordType: "A", ExpectedAnswers: []string{"10.0.0.2", "10.0.0.1"}},
+			"",
+		},
+		{
+			"expected answer missing",
+			options.DnsCheck{Name: "db.health-checker.test", Server: server, RecordType: "A", ExpectedAnswers: []string{"10.0.0.3"}},
+			"expected answer 10.0.0.3 was not found",
+		},
+		{
+			"unknown name",
+			options.DnsCheck{Name: "missing.health-checker.test", Server: server, RecordType: "A"},
+			"no such host",
+		},
+		{
+			"names in /etc/hosts are still sent to the server",
+			options.DnsCheck{Name: "localhost", Server: server, RecordType: "A"},
+			"no such host",
+		},
+		{
+			"expected answers in a different case",
+			options.DnsCheck{Name: "www.health-checker.test", Server: server, RecordType: "CNAME", ExpectedAnswers: []string{"DB.Health-Checker.test."}},
+			"",
+		},
+		{
+			"cname",
+			options.DnsCheck{Name: "www.health-checker.test", Server: server, RecordType: "CNAME", ExpectedAnswers: []string{"db.health-checker.test"}},
+			"",
+		},
+		{
+			"no cname",
+			options.DnsCheck{Name: "db.health-checker.test", Server: server, RecordType: "CNAME"},
+			"returned no answers",
+		},
+		{
+			"within max latency",
+			options.DnsCheck{Name: "db.health-checker.test", Server: server, RecordType: "A", MaxLatency: time.Second},
+			"",
+		},
+		{
+			"exceeds max latency",
+			options.DnsCheck{Name: "db.health-checker.test", Server: slowServer, RecordType: "A", MaxLatency: 50 * time.Millisecond},
+			"which is more than the maximum",
+		},
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	for _, testCase := range testCases {
+		err := attemptDnsLookup(testCase.dns, opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err, testCase.name)
+		} else if assert.NotNil(t, err, testCase.name) {
+			assert.Contains(t, err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestQueryDnsServerFallsBackToTcp(t *testing.T) {
+	t.Parallel()
+
+	records := map[string][]string{"db.health-checker.test.": {"10.0.0.1", "10.0.0.2"}}
+
+	// The UDP and TCP servers have to share a port, since the check sends both queries to the same address
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	t.Cleanup(func() { listener.Close() })
+	conn, err := net.ListenPacket("udp", listener.Addr().String())
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	// Over UDP, send a stray reply to another query, which must be ignored, and then an empty reply with the TC bit set
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			reply := fakeDnsReply(buf[:n], nil)
+			binary.BigEndian.PutUint16(reply, binary.BigEndian.Uint16(reply)+1)
+			conn.WriteTo(reply, addr)
+			binary.BigEndian.PutUint16(reply, binary.BigEndian.Uint16(reply)-1)
+			binary.BigEndian.PutUint16(reply[2:], 0x8180|dnsTruncated)
+			conn.WriteTo(reply, addr)
+		}
+	}()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			length := make([]byte, 2)
+			if _, err := io.ReadFull(conn, length); err == nil {
+				query := make([]byte, binary.BigEndian.Uint16(length))
+				if _, err := io.ReadFull(conn, query); err == nil {
+					reply := fakeDnsReply(query, records)
+					conn.Write(append([]byte{byte(len(reply) >> 8), byte(len(reply))}, reply...))
+				}
+			}
+			conn.Close()
+		}
+	}()
+
+	answers, err := queryDnsServer(context.Background(), listener.Addr().String(), "A", "db.health-checker.test")
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, answers)
+	}
+}
+
+func TestParseDnsReply(t *testing.T) {
+	t.Parallel()
+
+	mailName := append([]byte{0, 10}, encodeDnsName("mail.example.test")...)
+	compressedMailName := []byte{0, 20, 4, 'm', 'x', '0', '2', 0xc0, 12}
+
+	testCases := []struct {
+		name            string
+		queryType       uint16
+		reply           []byte
+		expectedAnswers []string
+		expectedErr     string
+	}{
+		{
+			"a",
+			dnsTypeA,
+			dnsTestMessage(0x8180, dnsTypeA, dnsTestAnswer(dnsTypeA, 10, 0, 0, 1)),
+			[]string{"10.0.0.1"},
+			"",
+		},
+		{
+			"aaaa",
+			dnsTypeAAAA,
+			dnsTestMessage(0x8180, dnsTypeAAAA, dnsTestAnswer(dnsTypeAAAA, net.ParseIP("2001:db8::1")...)),
+			[]string{"2001:db8::1"},
+			"",
+		},
+		{
+			"a with an ipv6 address",
+			dnsTypeA,
+			dnsTestMessage(0x8180, dnsTypeA, dnsTestAnswer(dnsTypeA, net.ParseIP("2001:db8::1")...)),
+			nil,
+			"an address has the wrong length",
+		},
+		{
+			"aaaa with an ipv4 address",
+			dnsTypeAAAA,
+			dnsTestMessage(0x8180, dnsTypeAAAA, dnsTestAnswer(dnsTypeAAAA, 10, 0, 0, 1)),
+			nil,
+			"an address has the wrong length",
+		},
+		{
+			"cname before the a record is skipped",
+			dnsTypeA,
+			dnsTestMessage(0x8180, dnsTypeA,
+				dnsTestAnswer(dnsTypeCNAME, encodeDnsName("db.example.test")...),
+				dnsTestAnswer(dnsTypeA, 10, 0, 0, 1),
+			),
+			[]string{"10.0.0.1"},
+			"",
+		},
+		{
+			"mx",
+			dnsTypeMX,
+			dnsTestMessage(0x8180, dnsTypeMX, dnsTestAnswer(dnsTypeMX, mailName...), dnsTestAnswer(dnsTypeMX, compressedMailName...)),
+			[]string{"mail.example.test.", "mx02.example.test."},
+			"",
+		},
+		{
+			"ns",
+			dnsTypeNS,
+			dnsTestMessage(0x8180, dnsTypeNS,
+				dnsTestAnswer(dnsTypeNS, 3, 'n', 's', '1', 0xc0, 12),
+				dnsTestAnswer(dnsTypeNS, encodeDnsName("ns2.example.net")...),
+			),
+			[]string{"ns1.example.test.", "ns2.example.net."},
+			"",
+		},
+		{
+			"txt made of several strings",
+			dnsTypeTXT,
+			dnsTestMessage(0x8180, dnsTypeTXT,
+				dnsTestAnswer(dnsTypeTXT, append([]byte{5}, "hello"...)...),
+				dnsTestAnswer(dnsTypeTXT, append(append([]byte{7}, "v=spf1 "...), append([]byte{4}, "-all"...)...)...),
+			),
+			[]string{"hello", "v=spf1 -all"},
+			"",
+		},
+		{
+			"truncated txt",
+			dnsTypeTXT,
+			dnsTestMessage(0x8180, dnsTypeTXT, dnsTestAnswer(dnsTypeTXT, 10, 'v', '=')),
+			nil,
+			"a TXT record is truncated",
+		},
+		{
+			"name error",
+			dnsTypeA,
+			dnsTestMessage(0x8183, dnsTypeA),
+			nil,
+			"no such host",
+		},
+		{
+			"server failure",
+			dnsTypeA,
+			dnsTestMessage(0x8182, dnsTypeA),
+			nil,
+			"DNS server replied with error code 2",
+		},
+		{
+			"truncated header",
+			dnsTypeA,
+			[]byte{0x12, 0x34, 0x81, 0x80},
+			nil,
+			"the header is truncated",
+		},
+		{
+			"missing answer",
+			dnsTypeA,
+			dnsTestMessage(0x8180, dnsTypeA, dnsTestAnswer(dnsTypeA, 10, 0, 0, 1))[:29],
+			nil,
+			"a name is truncated",
+		},
+		{
+			"truncated answer",
+			dnsTypeA,
+			dnsTestMessage(0x8180, dnsTypeA, dnsTestAnswer(dnsTypeA, 10, 0, 0, 1))[:40],
+			nil,
+			"an answer is truncated",
+		},
+		{
+			"truncated answer data",
+			dnsTypeA,
+			dnsTestMessage(0x8180, dnsTypeA, dnsTestAnswer(dnsTypeA, 10, 0, 0, 1))[:44],
+			nil,
+			"an answer is truncated",
+		},
+		{
+			"mx with an invalid pointer",
+			dnsTypeMX,
+			dnsTestMessage(0x8180, dnsTypeMX, dnsTestAnswer(dnsTypeMX, 0, 10, 0xc0, 0xff)),
+			nil,
+			"a name has an invalid pointer",
+		},
+	}
+
+	for _, testCase := range testCases {
+		answers, err := parseDnsReply(testCase.reply, testCase.queryType)
+		if testCase.expectedErr == "" {
+			if assert.Nil(t, err, testCase.name) {
+				assert.Equal(t, testCase.expectedAnswers, answers, testCase.name)
+			}
+		} else if assert.NotNil(t, err, testCase.name) {
+			assert.Contains(t, err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestReadDnsName(t *testing.T) {
+	t.Parallel()
+
+	example := encodeDnsName("example.test")
+
+	testCases := []struct {
+		name         string
+		message      []byte
+		offset       int
+		expectedName string
+		expectedEnd  int
+		expectedErr  string
+	}{
+		{"name", example, 0, "example.test.", 14, ""},
+		{"root", []byte{0}, 0, ".", 1, ""},
+		{"pointer", append(append([]byte{}, example...), 3, 'w', 'w', 'w', 0xc0, 0), 14, "www.example.test.", 20, ""},
+		{"pointer to a pointer", append(append([]byte{}, example...), 0xc0, 0, 3, 'w', 'w', 'w', 0xc0, 14), 16, "www.example.test.", 22, ""},
+		{"pointer to itself", []byte{0xc0, 0}, 0, "", 0, "a name has an invalid pointer"},
+		{"pointer loop", []byte{0xc0, 2, 0xc0, 0}, 2, "", 0, "a name has an invalid pointer"},
+		{"forward pointer", []byte{0xc0, 2, 0}, 0, "", 0, "a name has an invalid pointer"},
+		{"truncated label", []byte{5, 'a', 'b'}, 0, "", 0, "a name is truncated"},
+		{"truncated pointer", []byte{1, 'a', 0xc0}, 0, "", 0, "a name is truncated"},
+		{"missing terminator", []byte{1, 'a'}, 0, "", 0, "a name is truncated"},
+	}
+
+	for _, testCase := range testCases {
+		name, end, err := readDnsName(testCase.message, testCase.offset)
+		if testCase.expectedErr == "" {
+			if assert.Nil(t, err, testCase.name) {
+				assert.Equal(t, testCase.expectedName, name, testCase.name)
+				assert.Equal(t, testCase.expectedEnd, end, testCase.name)
+			}
+		} else if assert.NotNil(t, err, testCase.name) {
+			assert.Contains(t, err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+// Build a reply with the ID 0x1234, the given flags, a question for example.test. of the given type at offset 12, and
+// the given answers
+func dnsTestMessage(flags uint16, queryType uint16, answers ...[]byte) []byte {
+	message := []byte{0x12, 0x34, byte(flags >> 8), byte(flags), 0, 1, 0, byte(len(answers)), 0, 0, 0, 0}
+	message = append(message, encodeDnsName("example.test")...)
+	message = append(message, byte(queryType>>8), byte(queryType), 0, dnsClassInternet)
+	for _, answer := range answers {
+		message = append(message, answer...)
+	}
+	return message
+}
+
+// Build an answer for the name in the question of dnsTestMessage, with class IN, a TTL of 60 and the given data
+func dnsTestAnswer(recordType uint16, data ...byte) []byte {
+	answer := []byte{0xc0, 12, byte(recordType >> 8), byte(recordType), 0, dnsClassInternet, 0, 0, 0, 60}
+	answer = append(answer, byte(len(data)>>8), byte(len(data)))
+	return append(answer, data...)
+}
+
+func encodeDnsName(name string) []byte {
+	encoded := []byte{}
+	for _, label := range strings.Split(name, ".") {
+		encoded = append(append(encoded, byte(len(label))), label...)
+	}
+	return append(encoded, 0)
+}
+
+// Start a minimal DNS server on a random UDP port that answers A and CNAME queries for the given names, and NXDOMAIN
+// for everything else. Answers that are IP addresses are A records, and the others are CNAME records. Each reply is
+// delayed by the given amount. Returns the address of the server.
+func startFakeDnsServer(t *testing.T, records map[string][]string, delay time.Duration) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		assert.FailNow(t, "Failed to start fake DNS server: %s", err.Error())
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			reply := fakeDnsReply(buf[:n], records)
+			if reply == nil {
+				continue
+			}
+			time.Sleep(delay)
+			conn.WriteTo(reply, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func fakeDnsReply(query []byte, records map[string][]string) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+
+	// Read the name in the question section, which starts right after the 12 byte header
+	labels := []string{}
+	offset := 12
+	for offset < len(query) && query[offset] != 0 {
+		length := int(query[offset])
+		if offset+1+length > len(query) {
+			return nil
+		}
+		labels = append(labels, string(query[offset+1:offset+1+length]))
+		offset += 1 + length
+	}
+	// Skip the terminating zero length label, the type and the class
+	questionEnd := offset + 5
+	if questionEnd > len(query) {
+		return nil
+	}
+	name := strings.ToLower(strings.Join(labels, ".")) + "."
+	queryType := binary.BigEndian.Uint16(query[offset+1 : offset+3])
+
+	found := false
+	answers := []string{}
+	for _, answer := range records[name] {
+		found = true
+		isAddress := net.ParseIP(answer) != nil
+		if (isAddress && queryType == 1) || (!isAddress && queryType == 5) {
+			answers = append(answers, answer)
+		}
+	}
+
+	reply := make([]byte, 12, 512)
+	copy(reply, query[:2])
+	flags := uint16(0x8180)
+	if !found {
+		flags |= 3 // NXDOMAIN
+	}
+	binary.BigEndian.PutUint16(reply[2:], flags)
+	binary.BigEndian.PutUint16(reply[4:], 1)
+	binary.BigEndian.PutUint16(reply[6:], uint16(len(answers)))
+	reply = append(reply, query[12:questionEnd]...)
+
+	for _, answer := range answers {
+		// A pointer to the name in the question, then the type, class IN, a TTL of 60 and the address or name
+		if ip := net.ParseIP(answer); ip != nil {
+			reply = append(reply, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+			reply = append(reply, ip.To4()...)
+			continue
+		}
+		data := []byte{}
+		for _, label := range strings.Split(strings.TrimSuffix(answer, "."), ".") {
+			data = append(append(data, byte(len(label))), label...)
+		}
+		data = append(data, 0)
+		reply = append(reply, 0xc0, 12, 0, 5, 0, 1, 0, 0, 0, 60, 0, byte(len(data)))
+		reply = append(reply, data...)
+	}
+
+	return reply
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// The parts of a container in the Docker Engine API's list of containers that we use. Status is a description such as
+// "Up 2 hours (healthy)", which is the only place the list reports the result of a container's HEALTHCHECK.
+type dockerContainer struct {
+	Names  []string
+	State  string
+	Status string
+}
+
+// Check that enough of the containers identified by name or label are running, and healthy according to their own
+// HEALTHCHECK, if they have one
+type dockerCheck struct {
+	docker options.DockerCheck
+	opts   *options.Options
+}
+
+func (c *dockerCheck) Name() string {
+	return fmt.Sprintf("Docker containers %s", c.docker)
+}
+
+func (c *dockerCheck) Run() *checkResult {
+	return attemptDockerCheck(c.docker, c.opts)
+}
+
+func attemptDockerCheck(docker options.DockerCheck, opts *options.Options) *checkResult {
+	logger := opts.Logger
+	logger.Infof("Attempting to list containers %s via %s...", docker, docker.Socket)
+
+	// Let Docker find the matching containers, so that we make a single request however many containers there are
+	filters := map[string][]string{}
+	if docker.Name != "" {
+		filters["name"] = []string{"^/" + regexp.QuoteMeta(docker.Name) + "$"}
+	}
+	if len(docker.Labels) > 0 {
+		filters["label"] = docker.Labels
+	}
+	encodedFilters, err := json.Marshal(filters)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	containers := []dockerContainer{}
+	client := newHttpClient(docker.Socket, defaultCheckTimeout)
+	if err := getDockerApi(client, "/containers/json?all=1&filters="+url.QueryEscape(string(encodedFilters)), &containers); err != nil {
+		return &checkResult{Err: err}
+	}
+
+	healthy := 0
+	unhealthy := []string{}
+	for _, container := range containers {
+		name := ""
+		if len(container.Names) > 0 {
+			name = strings.TrimPrefix(container.Names[0], "/")
+		}
+		switch {
+		case container.State != "running":
+			unhealthy = append(unhealthy, fmt.Sprintf("%s is %s", name, container.State))
+		case strings.HasSuffix(container.Status, "(unhealthy)"):
+			unhealthy = append(unhealthy, fmt.Sprintf("%s is unhealthy", name))
+		case strings.HasSuffix(container.Status, "(health: starting)"):
+			unhealthy = append(unhealthy, fmt.Sprintf("%s is starting", name))
+		default:
+			healthy++
+		}
+	}
+
+	result := &checkResult{Details: map[string]string{
+		"matched": strconv.Itoa(len(containers)),
+		"healthy": strconv.Itoa(healthy),
+	}}
+	if healthy < docker.Min {
+		result.Err = TooFewHealthyContainers{healthy: healthy, min: docker.Min, unhealthy: unhealthy}
+	}
+	return result
+}
+
+// Send a GET request to the Docker Engine API and decode the JSON response into v
+func getDockerApi(client *http.Client, path string, v interface{}) error {
+	response, err := client.Get("http://docker" + path)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+	// Read whatever the decoder or an error left of the body, so that the next check can reuse the connection
+	defer io.Copy(ioutil.Discard, io.LimitReader(response.Body, maxResponseSize))
+
+	if response.StatusCode != http.StatusOK {
+		return UnexpectedHttpStatus(response.StatusCode)
+	}
+	return json.NewDecoder(response.Body).Decode(v)
+}
+
+// Custom error types
+
+type TooFewHealthyContainers struct {
+	healthy   int
+	min       int
+	unhealthy []string
+}
+
+func (err TooFewHealthyContainers) Error() string {
+	message := fmt.Sprintf("found %d healthy containers, expected at least %d", err.healthy, err.min)
+	if len(err.unhealthy) > 0 {
+		message += ": " + strings.Join(err.unhealthy, ", ")
+	}
+	return message
+}
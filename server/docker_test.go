@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptDockerCheck(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "health-checker-docker-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	// A fake Docker Engine API with two healthy api containers, an unhealthy api container, an exited worker and a
+	// database without a HEALTHCHECK. It only supports the filters we send, and fails any request for a single
+	// container, since the list has everything we need.
+	socket := filepath.Join(dir, "docker.sock")
+	containers := []struct {
+		Names  []string
+		Labels map[string]string
+		State  string
+		Status string
+	}{
+		{[]string{"/api-1"}, map[string]string{"service": "api"}, "running", "Up 2 hours (healthy)"},
+		{[]string{"/api-2"}, map[string]string{"service": "api"}, "running", "Up 2 hours (healthy)"},
+		{[]string{"/api-3"}, map[string]string{"service": "api", "canary": "true"}, "running", "Up 5 minutes (unhealthy)"},
+		{[]string{"/worker"}, map[string]string{"service": "worker"}, "exited", "Exited (1) 3 minutes ago"},
+		{[]string{"/db"}, map[string]string{}, "running", "Up 2 hours"},
+	}
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer l.Close()
+	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filters := map[string][]string{}
+		if r.URL.Path != "/containers/json" || r.URL.Query().Get("all") != "1" || json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters) != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		matches := []interface{}{}
+		for _, container := range containers {
+			matched := true
+			for _, name := range filters["name"] {
+				matched = matched && regexp.MustCompile(name).MatchString(container.Names[0])
+			}
+			for _, label := range filters["label"] {
+				keyAndValue := strings.SplitN(label, "=", 2)
+				value, ok := container.Labels[keyAndValue[0]]
+				matched = matched && ok && (len(keyAndValue) == 1 || value == keyAndValue[1])
+			}
+			if matched {
+				matches = append(matches, container)
+			}
+		}
+		json.NewEncoder(w).Encode(matches)
+	}))
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	testCases := []struct {
+		name        string
+		docker      options.DockerCheck
+		expectedErr string
+	}{
+		{"name", options.DockerCheck{Name: "api-1", Min: 1}, ""},
+		{"no healthcheck", options.DockerCheck{Name: "db", Min: 1}, ""},
+		{"exited", options.DockerCheck{Name: "worker", Min: 1}, "found 0 healthy containers, expected at least 1: worker is exited"},
+		{"name is not a prefix", options.DockerCheck{Name: "api", Min: 1}, "found 0 healthy containers, expected at least 1"},
+		{"label", options.DockerCheck{Labels: []string{"service=api"}, Min: 2}, ""},
+		{"label with too few healthy", options.DockerCheck{Labels: []string{"service=api"}, Min: 3}, "found 2 healthy containers, expected at least 3: api-3 is unhealthy"},
+		{"label key only", options.DockerCheck{Labels: []string{"canary"}, Min: 1}, "api-3 is unhealthy"},
+		{"name and label", options.DockerCheck{Name: "api-1", Labels: []string{"service=worker"}, Min: 1}, "found 0 healthy containers"},
+		{"missing socket", options.DockerCheck{Socket: filepath.Join(dir, "missing.sock"), Name: "api-1", Min: 1}, "no such file or directory"},
+	}
+
+	for _, testCase := range testCases {
+		if testCase.docker.Socket == "" {
+			testCase.docker.Socket = socket
+		}
+		result := attemptDockerCheck(testCase.docker, opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestDockerCheckReusesConnection(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "health-checker-docker-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer l.Close()
+
+	// The decoder stops reading at the end of the list, and an error stops us reading at all, so both leave part of the
+	// body unread
+	connections := make(chan struct{}, 100)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.URL.Query().Get("filters"), "broken") {
+				http.Error(w, strings.Repeat("page not found ", 3000), http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, `[{"Names":["/web"],"State":"running","Status":"Up 2 hours"}]`+strings.Repeat(" ", 40000))
+		}),
+		ConnState: func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				connections <- struct{}{}
+			}
+		},
+	}
+	go server.Serve(l)
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, attemptDockerCheck(options.DockerCheck{Socket: socket, Name: "web", Min: 1}, opts).Err)
+		assert.NotNil(t, attemptDockerCheck(options.DockerCheck{Socket: socket, Name: "broken", Min: 1}, opts).Err)
+	}
+	assert.Equal(t, 1, len(connections))
+}
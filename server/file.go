@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// Check that a file, such as a heartbeat or status file written by a batch job, exists and is fresh, and optionally
+// that it has the expected size and contents
+type fileCheck struct {
+	file options.FileCheck
+	opts *options.Options
+}
+
+func (c *fileCheck) Name() string {
+	return fmt.Sprintf("File %s", c.file.Path)
+}
+
+func (c *fileCheck) Run() *checkResult {
+	c.opts.Logger.Infof("Attempting to inspect %s...", c.file.Path)
+
+	info, err := os.Stat(c.file.Path)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	age := time.Since(info.ModTime())
+	size := uint64(info.Size())
+	result := &checkResult{Details: map[string]string{
+		"age":  age.Round(time.Second).String(),
+		"size": strconv.FormatUint(size, 10),
+	}}
+
+	result.Err = evaluateFile(c.file, info, age)
+	return result
+}
+
+// Check the age, size and contents of a file against the expectations in the given check
+func evaluateFile(file options.FileCheck, info os.FileInfo, age time.Duration) error {
+	if info.IsDir() {
+		return UnexpectedFileState(fmt.Sprintf("%s is a directory", file.Path))
+	}
+	if file.MaxAge > 0 && age > file.MaxAge {
+		return UnexpectedFileState(fmt.Sprintf("%s was last modified %s ago, expected at most %s", file.Path, age.Round(time.Second), file.MaxAge))
+	}
+
+	size := uint64(info.Size())
+	if size < file.MinSize {
+		return UnexpectedFileState(fmt.Sprintf("%s is %d bytes, expected at least %d", file.Path, size, file.MinSize))
+	}
+	if file.MaxSize > 0 && size > file.MaxSize {
+		return UnexpectedFileState(fmt.Sprintf("%s is %d bytes, expected at most %d", file.Path, size, file.MaxSize))
+	}
+
+	if file.Match == nil && file.JsonField == "" {
+		return nil
+	}
+
+	contents, err := ioutil.ReadFile(file.Path)
+	if err != nil {
+		return err
+	}
+
+	if file.Match != nil && !file.Match.Match(contents) {
+		return UnexpectedFileState(fmt.Sprintf("the contents of %s do not match %q", file.Path, file.Match))
+	}
+
+	if file.JsonField != "" {
+		var document interface{}
+		if err := json.Unmarshal(contents, &document); err != nil {
+			return UnexpectedFileState(fmt.Sprintf("%s does not contain valid JSON: %s", file.Path, err))
+		}
+		value, ok := lookupJsonPath(document, file.JsonField)
+		if !ok {
+			return UnexpectedFileState(fmt.Sprintf("%s has no JSON field %s", file.Path, file.JsonField))
+		}
+		if actual := formatJsonValue(value); actual != file.JsonValue {
+			return UnexpectedFileState(fmt.Sprintf("JSON field %s in %s is %q, expected %q", file.JsonField, file.Path, actual, file.JsonValue))
+		}
+	}
+
+	return nil
+}
+
+// Custom error types
+
+type UnexpectedFileState string
+
+func (reason UnexpectedFileState) Error() string {
+	return string(reason)
+}
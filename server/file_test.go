@@ -0,0 +1,73 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileCheck(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "health-checker-file-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	heartbeat := filepath.Join(dir, "agent.heartbeat")
+	status := filepath.Join(dir, "agent.json")
+	stale := filepath.Join(dir, "stale.heartbeat")
+	for path, contents := range map[string]string{
+		heartbeat: "",
+		status:    `{"status": {"state": "ok", "healthy": true, "jobs": [{"id": 7}]}}`,
+		stale:     "",
+	} {
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			assert.FailNow(t, "Failed to write file: %v", err.Error())
+		}
+	}
+	hourAgo := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(stale, hourAgo, hourAgo); err != nil {
+		assert.FailNow(t, "Failed to change mtime: %v", err.Error())
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	testCases := []struct {
+		name        string
+		file        options.FileCheck
+		expectedErr string
+	}{
+		{"exists", options.FileCheck{Path: heartbeat}, ""},
+		{"missing", options.FileCheck{Path: filepath.Join(dir, "missing")}, "no such file or directory"},
+		{"directory", options.FileCheck{Path: dir}, "is a directory"},
+		{"fresh", options.FileCheck{Path: heartbeat, MaxAge: time.Minute}, ""},
+		{"stale", options.FileCheck{Path: stale, MaxAge: time.Minute}, "was last modified 1h0m0s ago, expected at most 1m0s"},
+		{"too small", options.FileCheck{Path: heartbeat, MinSize: 1}, "is 0 bytes, expected at least 1"},
+		{"too large", options.FileCheck{Path: status, MaxSize: 10}, "expected at most 10"},
+		{"matches", options.FileCheck{Path: status, Match: regexp.MustCompile(`"state": "ok"`)}, ""},
+		{"does not match", options.FileCheck{Path: status, Match: regexp.MustCompile(`"state": "failed"`)}, "do not match"},
+		{"json string", options.FileCheck{Path: status, JsonField: "status.state", JsonValue: "ok"}, ""},
+		{"json bool", options.FileCheck{Path: status, JsonField: "status.healthy", JsonValue: "true"}, ""},
+		{"json array", options.FileCheck{Path: status, JsonField: "status.jobs.0.id", JsonValue: "7"}, ""},
+		{"json mismatch", options.FileCheck{Path: status, JsonField: "status.state", JsonValue: "failed"}, `JSON field status.state in ` + status + ` is "ok", expected "failed"`},
+		{"json missing field", options.FileCheck{Path: status, JsonField: "status.jobs.1.id", JsonValue: "7"}, "has no JSON field status.jobs.1.id"},
+		{"invalid json", options.FileCheck{Path: heartbeat, JsonField: "status", JsonValue: "ok"}, "does not contain valid JSON"},
+	}
+
+	for _, testCase := range testCases {
+		result := (&fileCheck{file: testCase.file, opts: opts}).Run()
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
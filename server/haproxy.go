@@ -0,0 +1,62 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// How long we wait to write a reply to an HAProxy agent before giving up on the connection
+const haproxyAgentWriteTimeout = time.Second * 5
+
+// Accept TCP connections from HAProxy's agent-check on the given address. Each connection triggers a pass of the
+// checks and receives a single line that tells HAProxy whether the server is up, and at what weight.
+func (runner *checkRunner) serveHaproxyAgent(address string) error {
+	logger := runner.opts.Logger
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	logger.Infof("Listening for HAProxy agent checks on %s...", address)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go runner.handleHaproxyAgentConnection(conn)
+	}
+}
+
+func (runner *checkRunner) handleHaproxyAgentConnection(conn net.Conn) {
+	defer conn.Close()
+
+	reply := haproxyAgentReply(runner.run())
+	runner.opts.Logger.Infof("Replying to HAProxy agent check with \"%s\"", reply)
+
+	conn.SetWriteDeadline(time.Now().Add(haproxyAgentWriteTimeout))
+	if _, err := fmt.Fprintf(conn, "%s\n", reply); err != nil {
+		runner.opts.Logger.Warnf("Failed to send reply to HAProxy agent check: %s", err)
+	}
+}
+
+// Convert check results into a reply in HAProxy's agent-check protocol. If no checks pass the server is reported as
+// down. If every check that passed is degraded, the server is drained, so that it finishes its existing sessions but gets
+// no new ones. Otherwise it is reported as up with a weight equal to the percentage of checks that passed. HAProxy keeps
+// a server drained until it is told otherwise, so an up reply also marks the server as ready.
+func haproxyAgentReply(results []*checkResult) string {
+	passed := countPassed(results)
+	if passed == 0 {
+		return "down"
+	}
+
+	if countDegraded(results) == passed {
+		return "up drain"
+	}
+
+	return fmt.Sprintf("up ready %d%%", passed*100/len(results))
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHaproxyAgentReply(t *testing.T) {
+	t.Parallel()
+
+	passed := &checkResult{}
+	failed := &checkResult{Err: errors.New("failed")}
+	degraded := &checkResult{Warning: errors.New("degraded")}
+
+	testCases := []struct {
+		name          string
+		results       []*checkResult
+		expectedReply string
+	}{
+		{"all checks pass", []*checkResult{passed, passed}, "up ready 100%"},
+		{"some checks pass", []*checkResult{passed, failed, failed, passed}, "up ready 50%"},
+		{"weight is rounded down", []*checkResult{passed, failed, failed}, "up ready 33%"},
+		{"some passing checks degraded", []*checkResult{passed, degraded, failed}, "up ready 66%"},
+		{"all passing checks degraded", []*checkResult{degraded, degraded, failed}, "up drain"},
+		{"no checks pass", []*checkResult{failed, failed}, "down"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expectedReply, haproxyAgentReply(testCase.results), testCase.name)
+	}
+}
+
+func TestServeHaproxyAgent(t *testing.T) {
+	// Will *not* run parallel because we're opening random tcp ports
+	// and want to avoid port clashes
+	ports, err := test.GetFreePorts(3)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	l, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[1]))
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	go handleRequests(t, l, nil)
+	defer l.Close()
+
+	// Only the first of the two checked ports is listening, so half the checks should pass
+	opts := createOptionsForTest(t, 5, []string{}, test.DEFAULT_LISTENER_ADDRESS, ports[1:])
+	agentListener := test.ListenerString("127.0.0.1", ports[0])
+	go newCheckRunner(opts).serveHaproxyAgent(agentListener)
+
+	var conn net.Conn
+	for i := 0; i < 50; i++ {
+		conn, err = net.Dial("tcp", agentListener)
+		if err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		assert.FailNow(t, "Failed to connect to HAProxy agent listener: %s", err.Error())
+	}
+	defer conn.Close()
+
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "up ready 50%\n", reply)
+}
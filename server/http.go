@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// We only read this much of an HTTP response body, which is plenty for a health document
+const maxHttpBodySize = 1024 * 1024
+
+// Check that an HTTP endpoint responds with an expected status code and, optionally, a JSON body that satisfies a set
+// of assertions
+type httpCheck struct {
+	http options.HttpCheck
+	opts *options.Options
+}
+
+func (c *httpCheck) Name() string {
+	if c.http.UnixSocket != "" {
+		return fmt.Sprintf("HTTP %s %s via %s", c.http.Method, c.http.Url, c.http.UnixSocket)
+	}
+	return fmt.Sprintf("HTTP %s %s", c.http.Method, c.http.Url)
+}
+
+func (c *httpCheck) Run() *checkResult {
+	return attemptHttpRequest(c.http, c.opts)
+}
+
+// Send the request in the given check and evaluate the response
+func attemptHttpRequest(check options.HttpCheck, opts *options.Options) *checkResult {
+	logger := opts.Logger
+	logger.Infof("Attempting to send a %s request to %s...", check.Method, check.Url)
+
+	request, err := http.NewRequest(check.Method, check.Url, nil)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	client := newHttpClient(check.UnixSocket, check.Timeout)
+	response, err := client.Do(request)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, maxHttpBodySize))
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+	logger.Debugf("Received %d byte response from %s: %q", len(body), check.Url, body)
+
+	result := &checkResult{Details: map[string]string{"status": strconv.Itoa(response.StatusCode)}}
+	if !check.MatchesStatus(response.StatusCode) {
+		result.Err = UnexpectedHttpStatus(response.StatusCode)
+		return result
+	}
+
+	if len(check.JsonAssertions) > 0 {
+		var document interface{}
+		if err := json.Unmarshal(body, &document); err != nil {
+			result.Err = InvalidJsonBody{err: err}
+			return result
+		}
+		result.Err, result.Warning = evaluateJsonAssertions(document, check.JsonAssertions)
+	}
+
+	return result
+}
+
+// How long a connection to a Unix domain socket is kept open for the next check to reuse
+const unixSocketIdleTimeout = time.Minute
+
+// The checks are rebuilt for every request, so the transports for Unix domain sockets, which hold on to idle
+// connections, are kept here instead, so that each check reuses its connection rather than leaking a new one every time
+var unixSocketTransports = struct {
+	sync.Mutex
+	bySocket map[string]*http.Transport
+}{bySocket: map[string]*http.Transport{}}
+
+// Return an HTTP client that sends requests over the Unix domain socket at the given path, if it is set, or to the host
+// in each request's URL otherwise
+func newHttpClient(unixSocket string, timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if unixSocket != "" {
+		client.Transport = getUnixSocketTransport(unixSocket)
+	}
+	return client
+}
+
+// Return the transport for the given Unix domain socket, creating it the first time it is needed
+func getUnixSocketTransport(unixSocket string) *http.Transport {
+	unixSocketTransports.Lock()
+	defer unixSocketTransports.Unlock()
+
+	transport, ok := unixSocketTransports.bySocket[unixSocket]
+	if !ok {
+		transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				dialer := net.Dialer{}
+				return dialer.DialContext(ctx, "unix", unixSocket)
+			},
+			IdleConnTimeout: unixSocketIdleTimeout,
+		}
+		unixSocketTransports.bySocket[unixSocket] = transport
+	}
+	return transport
+}
+
+// Custom error types
+
+type UnexpectedHttpStatus int
+
+func (status UnexpectedHttpStatus) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d %s", int(status), http.StatusText(int(status)))
+}
+
+type InvalidJsonBody struct {
+	err error
+}
+
+func (err InvalidJsonBody) Error() string {
+	return fmt.Sprintf("the response body is not valid JSON: %s", err.err)
+}
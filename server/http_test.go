@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptHttpRequest(t *testing.T) {
+	t.Parallel()
+
+	// A fake Elasticsearch that reports its cluster health
+	status := "yellow"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_cluster/health":
+			fmt.Fprintf(w, `{"cluster_name": "logs", "status": "%s", "active_shards": 12, "unassigned_shards": "3", "nodes": [{"name": "es-1"}]}`, status)
+		case "/text":
+			fmt.Fprint(w, "OK")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	health := server.URL + "/_cluster/health"
+
+	testCases := []struct {
+		name            string
+		url             string
+		status          []int
+		assertions      []string
+		warnAssertions  []string
+		expectedErr     string
+		expectedWarning string
+	}{
+		{"status only", health, nil, nil, nil, "", ""},
+		{"not found", server.URL + "/missing", nil, nil, nil, "unexpected HTTP status 404 Not Found", ""},
+		{"expected status", server.URL + "/missing", []int{404}, nil, nil, "", ""},
+		{"equals", health, nil, []string{"cluster_name == logs"}, nil, "", ""},
+		{"in set", health, nil, []string{"status in green|yellow"}, nil, "", ""},
+		{"degraded", health, nil, []string{"status in green|yellow"}, []string{"status == green"}, "", `expected status == green but status is "yellow"`},
+		{"failed", health, nil, []string{"status == green"}, nil, `expected status == green but status is "yellow"`, ""},
+		{"numeric", health, nil, []string{"active_shards >= 10", "unassigned_shards < 5"}, nil, "", ""},
+		{"numeric failure", health, nil, []string{"active_shards > 20"}, nil, "expected active_shards > 20 but active_shards is 12", ""},
+		{"reference", health, nil, []string{"unassigned_shards < ${active_shards}"}, nil, "", ""},
+		{"reference failure", health, nil, []string{"active_shards <= ${unassigned_shards}"}, nil, "expected active_shards <= ${unassigned_shards} but active_shards is 12", ""},
+		{"missing reference", health, nil, []string{"active_shards < ${max_shards}"}, nil, "expected active_shards < ${max_shards} but max_shards is missing", ""},
+		{"exists", health, nil, []string{"nodes.0.name exists"}, nil, "", ""},
+		{"missing", health, nil, []string{"nodes.1.name exists"}, nil, "expected nodes.1.name exists but nodes.1.name is missing", ""},
+		{"not equals", health, nil, []string{"status != red", "relocating != true"}, nil, "", ""},
+		{"not json", server.URL + "/text", nil, []string{"status exists"}, nil, "the response body is not valid JSON", ""},
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	for _, testCase := range testCases {
+		check := options.HttpCheck{Url: testCase.url, Method: http.MethodGet, ExpectStatus: testCase.status, Timeout: defaultCheckTimeout}
+		for _, s := range testCase.assertions {
+			assertion, err := options.ParseJsonAssertion(s, false)
+			assert.Nil(t, err, testCase.name)
+			check.JsonAssertions = append(check.JsonAssertions, assertion)
+		}
+		for _, s := range testCase.warnAssertions {
+			assertion, err := options.ParseJsonAssertion(s, true)
+			assert.Nil(t, err, testCase.name)
+			check.JsonAssertions = append(check.JsonAssertions, assertion)
+		}
+
+		result := attemptHttpRequest(check, opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+		}
+		if testCase.expectedWarning == "" {
+			assert.Nil(t, result.Warning, testCase.name)
+		} else if assert.NotNil(t, result.Warning, testCase.name) {
+			assert.Equal(t, testCase.expectedWarning, result.Warning.Error(), testCase.name)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// Look up the value at a dot separated path in a decoded JSON document, such as "status.state" or "items.0.name".
+// Array elements are addressed by their index. Returns false if there is no value at the path.
+func lookupJsonPath(document interface{}, path string) (interface{}, bool) {
+	value := document
+	if path == "" {
+		return value, true
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			child, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			value = child
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			value = node[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// Format a decoded JSON value for comparison with a value from a check spec. Strings are returned as is, so that
+// json-value=ok matches "ok", and everything else is returned as JSON, so that json-value=true matches true.
+func formatJsonValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// Evaluate assertions about a decoded JSON document. The first assertion that doesn't hold is returned as err, or as
+// warning if the assertion only degrades the check.
+func evaluateJsonAssertions(document interface{}, assertions []options.JsonAssertion) (err error, warning error) {
+	for _, assertion := range assertions {
+		failure := checkJsonAssertion(document, assertion)
+		switch {
+		case failure == nil:
+		case assertion.Warn && warning == nil:
+			warning = failure
+		case !assertion.Warn && err == nil:
+			err = failure
+		}
+	}
+	return err, warning
+}
+
+// Return an error naming the path and the actual value if the assertion doesn't hold for the document
+func checkJsonAssertion(document interface{}, assertion options.JsonAssertion) error {
+	value, found := lookupJsonPath(document, assertion.Path)
+	if !found {
+		if assertion.Operator == "!=" {
+			return nil
+		}
+		return JsonAssertionFailed{assertion: assertion}
+	}
+
+	actual := formatJsonValue(value)
+	var holds bool
+	switch assertion.Operator {
+	case "exists":
+		holds = true
+	case "==":
+		holds = actual == assertion.Values[0]
+	case "!=":
+		holds = actual != assertion.Values[0]
+	case "in":
+		holds = containsString(assertion.Values, actual)
+	default:
+		expected := assertion.Values[0]
+		if path, ok := assertion.Reference(); ok {
+			reference, found := lookupJsonPath(document, path)
+			if !found {
+				return JsonAssertionFailed{assertion: assertion, missingReference: path}
+			}
+			expected = formatJsonValue(reference)
+		}
+		holds = compareJsonNumber(value, assertion.Operator, expected)
+	}
+
+	if holds {
+		return nil
+	}
+	encoded, _ := json.Marshal(value)
+	return JsonAssertionFailed{assertion: assertion, found: true, actual: string(encoded)}
+}
+
+// Compare a JSON number, or a string containing a number, to expected using a numeric operator such as >=
+func compareJsonNumber(value interface{}, operator string, expected string) bool {
+	var actual float64
+	switch v := value.(type) {
+	case float64:
+		actual = v
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return false
+		}
+		actual = parsed
+	default:
+		return false
+	}
+
+	threshold, err := strconv.ParseFloat(expected, 64)
+	if err != nil {
+		return false
+	}
+	return options.CompareNumbers(actual, operator, threshold)
+}
+
+// Custom error types
+
+type JsonAssertionFailed struct {
+	assertion        options.JsonAssertion
+	found            bool
+	actual           string
+	missingReference string
+}
+
+func (err JsonAssertionFailed) Error() string {
+	if err.missingReference != "" {
+		return fmt.Sprintf("expected %s but %s is missing", err.assertion, err.missingReference)
+	}
+	if !err.found {
+		return fmt.Sprintf("expected %s but %s is missing", err.assertion, err.assertion.Path)
+	}
+	return fmt.Sprintf("expected %s but %s is %s", err.assertion, err.assertion.Path, err.actual)
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// Lines longer than this are split, so that a log file without newlines can't use up all our memory
+const maxLogLineSize = 64 * 1024
+
+// How often the log files are read in the background, which is how late a matching line may be timestamped
+const logTailInterval = time.Second
+
+// The number of buckets that the window of a check is divided into. Matches are counted per bucket rather than kept one
+// by one, so that a log that matches on every line can't use up our memory either.
+const logMatchBuckets = 60
+
+// The checks are rebuilt for every request, so the tailers, which remember how far into each log file they have read,
+// are kept here instead
+var logTailers = struct {
+	sync.Mutex
+	byCheck map[string]*logTailer
+}{byCheck: map[string]*logTailer{}}
+
+// Check that a log file hasn't had too many lines matching a pattern within a sliding time window
+type logPatternCheck struct {
+	logPattern options.LogPatternCheck
+	opts       *options.Options
+}
+
+func (c *logPatternCheck) Name() string {
+	return fmt.Sprintf("Log pattern %q in %s", c.logPattern.Match, c.logPattern.Path)
+}
+
+func (c *logPatternCheck) Run() *checkResult {
+	c.opts.Logger.Infof("Attempting to read new lines in %s...", c.logPattern.Path)
+
+	tailer := getLogTailer(c.logPattern)
+	count, err := tailer.countMatches(time.Now())
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	result := &checkResult{Details: map[string]string{"matches": strconv.Itoa(count)}}
+	if count > c.logPattern.Max {
+		result.Err = TooManyLogMatches{check: c.logPattern, count: count}
+	}
+	return result
+}
+
+// Open the log files of all the log pattern checks, and keep reading them in the background, so that each matching line
+// is timestamped when it is written rather than when the next request happens to arrive. A file that can't be opened
+// yet is opened as soon as it appears.
+func startLogTailers(opts *options.Options) {
+	tailers := []*logTailer{}
+	for _, check := range opts.LogPatternChecks {
+		tailer := getLogTailer(check)
+		if _, err := tailer.countMatches(time.Now()); err != nil {
+			opts.Logger.Warnf("Failed to open %s, will keep trying: %s", check.Path, err)
+		}
+		tailers = append(tailers, tailer)
+	}
+
+	if len(tailers) > 0 {
+		go followLogs(opts, tailers, time.NewTicker(logTailInterval).C)
+	}
+}
+
+// Read the new lines of every tailer each time ticks fires
+func followLogs(opts *options.Options, tailers []*logTailer, ticks <-chan time.Time) {
+	for now := range ticks {
+		for _, tailer := range tailers {
+			if _, err := tailer.countMatches(now); err != nil {
+				opts.Logger.Debugf("Failed to read %s: %s", tailer.check.Path, err)
+			}
+		}
+	}
+}
+
+// Return the tailer for the given check, creating it the first time it is needed
+func getLogTailer(check options.LogPatternCheck) *logTailer {
+	key := fmt.Sprintf("%s\x00%s\x00%s", check.Path, check.Match, check.Window)
+
+	logTailers.Lock()
+	defer logTailers.Unlock()
+
+	tailer, ok := logTailers.byCheck[key]
+	if !ok {
+		tailer = &logTailer{check: check}
+		logTailers.byCheck[key] = tailer
+	}
+	return tailer
+}
+
+// Follows a log file like tail -F. The first time it opens the file, it starts at the end, so only lines written after
+// that are counted. If the file is truncated, it starts again from the beginning, and if the file is rotated, it
+// finishes reading the old file and then reads the new one from the beginning.
+type logTailer struct {
+	check   options.LogPatternCheck
+	mutex   sync.Mutex
+	file    *os.File
+	offset  int64
+	partial []byte
+	// The number of matching lines read in each bucket of the window, oldest first
+	buckets []logMatchBucket
+}
+
+type logMatchBucket struct {
+	start time.Time
+	count int
+}
+
+// Read any new lines and return the number of matching lines that were read within the window before now
+func (tailer *logTailer) countMatches(now time.Time) (int, error) {
+	tailer.mutex.Lock()
+	defer tailer.mutex.Unlock()
+
+	if err := tailer.readNewLines(now); err != nil {
+		return 0, err
+	}
+
+	cutoff := now.Add(-tailer.check.Window)
+	for len(tailer.buckets) > 0 && !tailer.buckets[0].start.After(cutoff) {
+		tailer.buckets = tailer.buckets[1:]
+	}
+
+	count := 0
+	for _, bucket := range tailer.buckets {
+		count += bucket.count
+	}
+	return count, nil
+}
+
+// Count a matching line read at now. A line read during the latest bucket, or before it, which happens when a check
+// and the background reader race, is counted in that bucket.
+func (tailer *logTailer) recordMatch(now time.Time) {
+	width := tailer.check.Window / logMatchBuckets
+	if n := len(tailer.buckets); n > 0 && now.Before(tailer.buckets[n-1].start.Add(width)) {
+		tailer.buckets[n-1].count++
+		return
+	}
+	tailer.buckets = append(tailer.buckets, logMatchBucket{start: now, count: 1})
+}
+
+func (tailer *logTailer) readNewLines(now time.Time) error {
+	if tailer.file == nil {
+		file, err := os.Open(tailer.check.Path)
+		if err != nil {
+			return err
+		}
+		offset, err := file.Seek(0, io.SeekEnd)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		tailer.file = file
+		tailer.offset = offset
+		return nil
+	}
+
+	info, err := tailer.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < tailer.offset {
+		// The file was truncated, e.g. by logrotate's copytruncate, so start again from the beginning
+		if err := tailer.rewind(tailer.file); err != nil {
+			return err
+		}
+	}
+
+	if err := tailer.readToEnd(now); err != nil {
+		return err
+	}
+
+	current, err := os.Stat(tailer.check.Path)
+	if err != nil || os.SameFile(info, current) {
+		// If the path doesn't exist, the file was rotated and the new one hasn't been created yet, so keep following the
+		// old one for now
+		return nil
+	}
+
+	// The file was rotated. We've already read the rest of the old file, so switch to the new one.
+	file, err := os.Open(tailer.check.Path)
+	if err != nil {
+		return nil
+	}
+	tailer.file.Close()
+	if err := tailer.rewind(file); err != nil {
+		return err
+	}
+	return tailer.readToEnd(now)
+}
+
+func (tailer *logTailer) rewind(file *os.File) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	tailer.file = file
+	tailer.offset = 0
+	tailer.partial = nil
+	return nil
+}
+
+// Read from the current offset to the end of the file, counting each matching line
+func (tailer *logTailer) readToEnd(now time.Time) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := tailer.file.Read(buf)
+		tailer.offset += int64(n)
+		tailer.partial = append(tailer.partial, buf[:n]...)
+
+		for {
+			i := bytes.IndexByte(tailer.partial, '\n')
+			if i < 0 && len(tailer.partial) < maxLogLineSize {
+				break
+			}
+			if i < 0 {
+				i = len(tailer.partial) - 1
+			}
+			if tailer.check.Match.Match(tailer.partial[:i+1]) {
+				tailer.recordMatch(now)
+			}
+			tailer.partial = tailer.partial[i+1:]
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Custom error types
+
+type TooManyLogMatches struct {
+	check options.LogPatternCheck
+	count int
+}
+
+func (err TooManyLogMatches) Error() string {
+	return fmt.Sprintf("%d lines in %s matched %q in the last %s, expected at most %d", err.count, err.check.Path, err.check.Match, err.check.Window, err.check.Max)
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogTailer(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "health-checker-log-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	writeLog(t, path, "FATAL before health-checker started\n", os.O_CREATE|os.O_WRONLY)
+
+	tailer := &logTailer{check: options.LogPatternCheck{Path: path, Match: regexp.MustCompile(`FATAL|OutOfMemoryError`), Window: time.Minute}}
+	start := time.Now()
+
+	// Lines that were already in the file are skipped
+	assertMatches(t, tailer, start, 0, "initial contents")
+
+	writeLog(t, path, "INFO ok\nFATAL one\njava.lang.OutOfMemoryError: Java heap space\nWARN partial FATAL", os.O_APPEND|os.O_WRONLY)
+	assertMatches(t, tailer, start.Add(10*time.Second), 2, "appended lines")
+
+	// The partial line is only matched once it is complete
+	writeLog(t, path, " line\n", os.O_APPEND|os.O_WRONLY)
+	assertMatches(t, tailer, start.Add(20*time.Second), 3, "completed line")
+
+	// The first two matches fall out of the window
+	assertMatches(t, tailer, start.Add(75*time.Second), 1, "sliding window")
+
+	// A truncated file is read again from the beginning
+	writeLog(t, path, "FATAL after truncation\n", os.O_TRUNC|os.O_WRONLY)
+	assertMatches(t, tailer, start.Add(78*time.Second), 2, "truncation")
+
+	// After rotation, the rest of the old file is read, followed by the new file
+	writeLog(t, path, "FATAL before rotation\n", os.O_APPEND|os.O_WRONLY)
+	if err := os.Rename(path, path+".1"); err != nil {
+		assert.FailNow(t, "Failed to rotate log: %v", err.Error())
+	}
+	writeLog(t, path, "FATAL after rotation\nINFO ok\n", os.O_CREATE|os.O_WRONLY)
+	assertMatches(t, tailer, start.Add(85*time.Second), 3, "rotation")
+
+	// Lines appended to the new file are picked up
+	writeLog(t, path, "FATAL again\n", os.O_APPEND|os.O_WRONLY)
+	assertMatches(t, tailer, start.Add(200*time.Second), 1, "after rotation")
+}
+
+func TestLogPatternCheck(t *testing.T) {
+	t.Parallel()
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	missing := &logPatternCheck{logPattern: options.LogPatternCheck{Path: "testdata/missing.log", Match: regexp.MustCompile(`FATAL`), Window: time.Minute}, opts: opts}
+	result := missing.Run()
+	if assert.NotNil(t, result.Err) {
+		assert.Contains(t, result.Err.Error(), "no such file or directory")
+	}
+
+	err := TooManyLogMatches{check: options.LogPatternCheck{Path: "app.log", Match: regexp.MustCompile(`FATAL`), Window: 5 * time.Minute}, count: 2}
+	assert.Equal(t, `2 lines in app.log matched "FATAL" in the last 5m0s, expected at most 0`, err.Error())
+}
+
+func TestStartLogTailers(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "health-checker-log-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	writeLog(t, path, "FATAL before health-checker started\n", os.O_CREATE|os.O_WRONLY)
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+	opts.LogPatternChecks = []options.LogPatternCheck{{Path: path, Match: regexp.MustCompile(`FATAL`), Window: time.Minute}}
+	startLogTailers(opts)
+
+	// A line written after startup but before the check first runs is counted
+	writeLog(t, path, "FATAL after health-checker started\n", os.O_APPEND|os.O_WRONLY)
+	result := (&logPatternCheck{logPattern: opts.LogPatternChecks[0], opts: opts}).Run()
+	if assert.NotNil(t, result.Err) {
+		assert.Equal(t, "1", result.Details["matches"])
+	}
+}
+
+func TestFollowLogs(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "health-checker-log-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	writeLog(t, path, "", os.O_CREATE|os.O_WRONLY)
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+	tailer := &logTailer{check: options.LogPatternCheck{Path: path, Match: regexp.MustCompile(`FATAL`), Window: time.Minute}}
+	ticks := make(chan time.Time)
+	go followLogs(opts, []*logTailer{tailer}, ticks)
+	defer close(ticks)
+
+	// Each tick is only received once the previous one has been handled, so the last tick makes sure the line was read
+	start := time.Now()
+	ticks <- start
+	writeLog(t, path, "FATAL while nobody was asking\n", os.O_APPEND|os.O_WRONLY)
+	ticks <- start.Add(time.Second)
+	ticks <- start.Add(2 * time.Second)
+
+	// The first request in a long while doesn't count the line as fresh, because it was read when it was written
+	assertMatches(t, tailer, start.Add(30*time.Second), 1, "within the window")
+	assertMatches(t, tailer, start.Add(2*time.Minute), 0, "after an idle gap")
+}
+
+func TestLogTailerBoundsMemory(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "health-checker-log-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	writeLog(t, path, "", os.O_CREATE|os.O_WRONLY)
+
+	tailer := &logTailer{check: options.LogPatternCheck{Path: path, Match: regexp.MustCompile(`FATAL`), Window: time.Minute}}
+	start := time.Now()
+	assertMatches(t, tailer, start, 0, "initial contents")
+
+	// A match every 100ms for two windows only ever needs one bucket per second of the window
+	for i := 1; i <= 1200; i++ {
+		writeLog(t, path, "FATAL\n", os.O_APPEND|os.O_WRONLY)
+		if _, err := tailer.countMatches(start.Add(time.Duration(i) * 100 * time.Millisecond)); err != nil {
+			assert.FailNow(t, "Failed to count matches: %v", err.Error())
+		}
+	}
+	assert.True(t, len(tailer.buckets) <= logMatchBuckets, "%d buckets", len(tailer.buckets))
+	assertMatches(t, tailer, start.Add(120*time.Second), 600, "full window")
+}
+
+func writeLog(t *testing.T, path string, contents string, flag int) {
+	file, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		assert.FailNow(t, "Failed to open log: %v", err.Error())
+	}
+	defer file.Close()
+	if _, err := file.WriteString(contents); err != nil {
+		assert.FailNow(t, "Failed to write log: %v", err.Error())
+	}
+}
+
+func assertMatches(t *testing.T, tailer *logTailer, now time.Time, expected int, msg string) {
+	count, err := tailer.countMatches(now)
+	if assert.Nil(t, err, msg) {
+		assert.Equal(t, expected, count, msg)
+	}
+}
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// The protocol version that MySQL and MariaDB send in their initial handshake
+const mysqlProtocolVersion = 10
+
+// The user that we log in as when no user is configured. The server denies it access, but only once it is accepting
+// connections, which is all we want to know.
+const mysqlProbeUser = "health-checker"
+
+// The capabilities we ask for when logging in as mysqlProbeUser: CLIENT_LONG_PASSWORD, CLIENT_PROTOCOL_41,
+// CLIENT_SECURE_CONNECTION and CLIENT_PLUGIN_AUTH
+const mysqlClientCapabilities = 0x00000001 | 0x00000200 | 0x00008000 | 0x00080000
+
+// The utf8_general_ci character set, which every version of MySQL and MariaDB supports
+const mysqlUtf8GeneralCi = 33
+
+// The command that ends a session
+const mysqlComQuit = 0x01
+
+// The error code of ER_ACCESS_DENIED_ERROR
+const mysqlAccessDenied = 1045
+
+// Check that a MySQL server is accepting connections, and optionally that we can log in and run a query
+type mysqlCheck struct {
+	mysql options.MysqlCheck
+	opts  *options.Options
+}
+
+func (c *mysqlCheck) Name() string {
+	return fmt.Sprintf("MySQL check of %s", c.mysql.Address)
+}
+
+func (c *mysqlCheck) Run() *checkResult {
+	if c.mysql.User == "" {
+		return attemptMysqlHandshake(c.mysql, c.opts)
+	}
+	return attemptMysqlQuery(c.mysql, c.opts)
+}
+
+// Read the initial handshake that the server sends as soon as we connect, and then log in as mysqlProbeUser, much like
+// mysqladmin ping. A server that has too many connections, or has blocked our host, sends an error packet instead of the
+// handshake. We have to reply to the handshake rather than hang up, since MySQL counts every connection that ends
+// during the handshake against max_connect_errors, and blocks our host once there are too many. Being denied access
+// doesn't count, and means the server is accepting connections.
+func attemptMysqlHandshake(mysqlCheck options.MysqlCheck, opts *options.Options) *checkResult {
+	opts.Logger.Infof("Attempting to read the MySQL handshake from %s...", mysqlCheck.Address)
+
+	conn, err := net.DialTimeout("tcp", mysqlCheck.Address, defaultCheckTimeout)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(defaultCheckTimeout))
+
+	sequence, payload, err := readMysqlPacket(conn)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+	if len(payload) == 0 {
+		return &checkResult{Err: InvalidMysqlHandshake("the handshake is empty")}
+	}
+
+	var version string
+	switch payload[0] {
+	case 0xff:
+		return &checkResult{Err: parseMysqlError(payload)}
+	case mysqlProtocolVersion:
+		end := strings.IndexByte(string(payload[1:]), 0)
+		if end < 0 {
+			return &checkResult{Err: InvalidMysqlHandshake("the server version is not terminated")}
+		}
+		version = string(payload[1 : end+1])
+	default:
+		return &checkResult{Err: InvalidMysqlHandshake(fmt.Sprintf("unsupported protocol version %d", payload[0]))}
+	}
+	result := &checkResult{Details: map[string]string{"version": version}}
+
+	// The capabilities, maximum packet size and character set, followed by 23 bytes of filler, the user, an empty auth
+	// response and the auth plugin that it is for
+	response := &bytes.Buffer{}
+	binary.Write(response, binary.LittleEndian, uint32(mysqlClientCapabilities))
+	binary.Write(response, binary.LittleEndian, uint32(1<<24))
+	response.WriteByte(mysqlUtf8GeneralCi)
+	response.Write(make([]byte, 23))
+	response.WriteString(mysqlProbeUser + "\x00")
+	response.WriteByte(0)
+	response.WriteString("mysql_native_password\x00")
+
+	// The server may ask us to switch to another auth plugin, in which case we send it the same empty auth response
+	for attempt := 0; attempt < 3; attempt++ {
+		if err := writeMysqlPacket(conn, sequence+1, response.Bytes()); err != nil {
+			return &checkResult{Err: err}
+		}
+		sequence, payload, err = readMysqlPacket(conn)
+		if err != nil {
+			return &checkResult{Err: err}
+		}
+		if len(payload) == 0 {
+			return &checkResult{Err: InvalidMysqlHandshake("the reply to our login is empty")}
+		}
+
+		switch payload[0] {
+		case 0x00:
+			// The probe user exists and has no password, so say goodbye properly
+			writeMysqlPacket(conn, 0, []byte{mysqlComQuit})
+			return result
+		case 0xff:
+			mysqlErr := parseMysqlError(payload)
+			if mysqlErr.code == mysqlAccessDenied {
+				return result
+			}
+			return &checkResult{Err: mysqlErr}
+		case 0xfe:
+			response = &bytes.Buffer{}
+		default:
+			return &checkResult{Err: InvalidMysqlHandshake(fmt.Sprintf("unexpected reply to our login 0x%02x", payload[0]))}
+		}
+	}
+	return &checkResult{Err: InvalidMysqlHandshake("the server kept asking us to switch auth plugins")}
+}
+
+// Read a packet, which starts with a 3 byte little endian length and a sequence number, and return its sequence number
+// and payload
+func readMysqlPacket(reader io.Reader) (byte, []byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, nil, err
+	}
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	if length > maxResponseSize {
+		return 0, nil, InvalidMysqlHandshake("invalid packet length")
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[3], payload, nil
+}
+
+func writeMysqlPacket(writer io.Writer, sequence byte, payload []byte) error {
+	header := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), sequence}
+	_, err := writer.Write(append(header, payload...))
+	return err
+}
+
+// Log in with the configured user and query the server's version and, if a role is required, whether it is replicating
+// from another server. Querying the replication status needs the REPLICATION CLIENT privilege, so we don't ask for it
+// unless we have to.
+func attemptMysqlQuery(mysqlCheck options.MysqlCheck, opts *options.Options) *checkResult {
+	opts.Logger.Infof("Attempting to log in to MySQL at %s as %s...", mysqlCheck.Address, mysqlCheck.User)
+
+	config := mysql.NewConfig()
+	config.Net = "tcp"
+	config.Addr = mysqlCheck.Address
+	config.User = mysqlCheck.User
+	config.Passwd = mysqlCheck.Password
+	config.DBName = mysqlCheck.Database
+	config.Timeout = defaultCheckTimeout
+
+	db, err := sql.Open("mysql", config.FormatDSN())
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultCheckTimeout)
+	defer cancel()
+
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		return &checkResult{Err: err}
+	}
+	if len(mysqlCheck.Roles) == 0 {
+		return &checkResult{Details: map[string]string{"version": version}}
+	}
+
+	// SHOW REPLICA STATUS replaced SHOW SLAVE STATUS in MySQL 8.0.22, and returns no rows on a primary
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	defer rows.Close()
+
+	role := "primary"
+	if rows.Next() {
+		role = "replica"
+	}
+	if err := rows.Err(); err != nil {
+		return &checkResult{Err: err}
+	}
+	return checkDatabaseRole(mysqlCheck.Roles, version, role)
+}
+
+// Parse an error packet, which is 0xff followed by a 2 byte error code, an optional '#' and SQLSTATE, and a message
+func parseMysqlError(payload []byte) MysqlError {
+	mysqlErr := MysqlError{}
+	if len(payload) >= 3 {
+		mysqlErr.code = binary.LittleEndian.Uint16(payload[1:3])
+		message := payload[3:]
+		if len(message) >= 6 && message[0] == '#' {
+			message = message[6:]
+		}
+		mysqlErr.message = string(message)
+	}
+	return mysqlErr
+}
+
+// Custom error types
+
+type MysqlError struct {
+	code    uint16
+	message string
+}
+
+func (err MysqlError) Error() string {
+	return fmt.Sprintf("MySQL is not accepting connections: %s (error %d)", err.message, err.code)
+}
+
+type InvalidMysqlHandshake string
+
+func (reason InvalidMysqlHandshake) Error() string {
+	return fmt.Sprintf("received an invalid MySQL handshake: %s", string(reason))
+}
@@ -0,0 +1,351 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptMysqlHandshake(t *testing.T) {
+	// Will *not* run parallel because we're opening random tcp ports
+	// and want to avoid port clashes
+	ports, err := test.GetFreePorts(5)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	servers := map[int]func(l net.Listener){
+		// The probe user doesn't exist, or has a password
+		ports[0]: func(l net.Listener) { serveMysql(l, "s3cr3t", nil) },
+		// Someone has created the probe user without a password
+		ports[1]: func(l net.Listener) { serveMysql(l, "", nil) },
+		ports[2]: serveMysqlAuthSwitch,
+		ports[3]: func(l net.Listener) {
+			serveMysqlPacket(l, append([]byte{0xff, 0x10, 0x04}, []byte("#08004Too many connections")...))
+		},
+		ports[4]: func(l net.Listener) { serveMysqlPacket(l, []byte("HTTP/1.1 400 Bad Request\r\n")) },
+	}
+	for port, serve := range servers {
+		l, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, port))
+		if err != nil {
+			assert.FailNow(t, "Failed to start listening: %s", err.Error())
+		}
+		defer l.Close()
+		go serve(l)
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	testCases := []struct {
+		name            string
+		port            int
+		expectedErr     string
+		expectedVersion string
+	}{
+		{"access denied", ports[0], "", "8.0.32"},
+		{"logged in", ports[1], "", "8.0.32"},
+		{"auth switch", ports[2], "", "8.0.32"},
+		{"too many connections", ports[3], "MySQL is not accepting connections: Too many connections (error 1040)", ""},
+		{"not mysql", ports[4], "received an invalid MySQL handshake", ""},
+	}
+
+	for _, testCase := range testCases {
+		mysql := options.MysqlCheck{Address: test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, testCase.port)}
+		result := (&mysqlCheck{mysql: mysql, opts: opts}).Run()
+		if testCase.expectedErr == "" {
+			if assert.Nil(t, result.Err, testCase.name) {
+				assert.Equal(t, testCase.expectedVersion, result.Details["version"], testCase.name)
+			}
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestAttemptMysqlQuery(t *testing.T) {
+	// Will *not* run parallel because we're opening random tcp ports
+	// and want to avoid port clashes
+	ports, err := test.GetFreePorts(3)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+	primaryAddress := test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0])
+	replicaAddress := test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[1])
+	unprivilegedAddress := test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[2])
+
+	syntaxError := mysqlErrorPacket(1064, "42000", "You have an error in your SQL syntax")
+	servers := map[string]func(query string) [][]byte{
+		// A MySQL 8.0 primary, which has no replication status
+		primaryAddress: func(query string) [][]byte {
+			switch query {
+			case "SELECT VERSION()":
+				return mysqlResultSet([]string{"VERSION()"}, []string{"8.0.32"})
+			case "SHOW REPLICA STATUS":
+				return mysqlResultSet([]string{"Replica_IO_State"})
+			}
+			return [][]byte{syntaxError}
+		},
+		// A MySQL 5.7 replica, which only knows SHOW SLAVE STATUS
+		replicaAddress: func(query string) [][]byte {
+			switch query {
+			case "SELECT VERSION()":
+				return mysqlResultSet([]string{"VERSION()"}, []string{"5.7.41-log"})
+			case "SHOW SLAVE STATUS":
+				return mysqlResultSet([]string{"Slave_IO_State"}, []string{"Waiting for master to send event"})
+			}
+			return [][]byte{syntaxError}
+		},
+		// A server where the health-checker user lacks the REPLICATION CLIENT privilege
+		unprivilegedAddress: func(query string) [][]byte {
+			switch query {
+			case "SELECT VERSION()":
+				return mysqlResultSet([]string{"VERSION()"}, []string{"8.0.32"})
+			case "SHOW REPLICA STATUS", "SHOW SLAVE STATUS":
+				return [][]byte{mysqlErrorPacket(1227, "42000", "Access denied; you need (at least one of) the SUPER, REPLICATION CLIENT privilege(s) for this operation")}
+			}
+			return [][]byte{syntaxError}
+		},
+	}
+	for address, query := range servers {
+		l, err := net.Listen("tcp", address)
+		if err != nil {
+			assert.FailNow(t, "Failed to start listening: %s", err.Error())
+		}
+		defer l.Close()
+		go serveMysql(l, "s3cr3t", query)
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	testCases := []struct {
+		name            string
+		mysql           options.MysqlCheck
+		expectedErr     string
+		expectedDetails map[string]string
+	}{
+		{
+			"log in",
+			options.MysqlCheck{Address: primaryAddress, User: "health", Password: "s3cr3t"},
+			"",
+			map[string]string{"version": "8.0.32"},
+		},
+		{
+			"primary",
+			options.MysqlCheck{Address: primaryAddress, User: "health", Password: "s3cr3t", Roles: []string{"primary"}},
+			"",
+			map[string]string{"version": "8.0.32", "role": "primary"},
+		},
+		{
+			"replica before SHOW REPLICA STATUS",
+			options.MysqlCheck{Address: replicaAddress, User: "health", Password: "s3cr3t", Database: "app", Roles: []string{"replica"}},
+			"",
+			map[string]string{"version": "5.7.41-log", "role": "replica"},
+		},
+		{
+			"not a primary",
+			options.MysqlCheck{Address: replicaAddress, User: "health", Password: "s3cr3t", Roles: []string{"primary"}},
+			"the server is a replica, expected primary",
+			nil,
+		},
+		{
+			"log in without REPLICATION CLIENT",
+			options.MysqlCheck{Address: unprivilegedAddress, User: "health", Password: "s3cr3t"},
+			"",
+			map[string]string{"version": "8.0.32"},
+		},
+		{
+			"role without REPLICATION CLIENT",
+			options.MysqlCheck{Address: unprivilegedAddress, User: "health", Password: "s3cr3t", Roles: []string{"replica"}},
+			"REPLICATION CLIENT privilege(s)",
+			nil,
+		},
+		{
+			"wrong password",
+			options.MysqlCheck{Address: primaryAddress, User: "health", Password: "wrong"},
+			"Access denied for user 'health'",
+			nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		result := (&mysqlCheck{mysql: testCase.mysql, opts: opts}).Run()
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+			assert.Equal(t, testCase.expectedDetails, result.Details, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+// Accept connections on l, and send payload in a single packet to each one
+func serveMysqlPacket(l net.Listener, payload []byte) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		writeMysqlPacket(conn, 0, payload)
+		conn.Close()
+	}
+}
+
+// Accept connections on l, log each one in with mysql_native_password if it knows the password, and answer each of its
+// queries with the packets that query returns
+func serveMysql(l net.Listener, password string, query func(query string) [][]byte) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+
+			writeMysqlPacket(conn, 0, mysqlHandshakePacket(mysqlTestScramble))
+
+			user, authResponse, ok := readMysqlHandshakeResponse(reader)
+			if !ok {
+				return
+			}
+			if !bytes.Equal(authResponse, mysqlNativePassword(mysqlTestScramble, password)) {
+				writeMysqlPacket(conn, 2, mysqlErrorPacket(1045, "28000", "Access denied for user '"+user+"'@'localhost' (using password: YES)"))
+				return
+			}
+			writeMysqlPacket(conn, 2, []byte{0, 0, 0, 2, 0, 0, 0})
+
+			for {
+				_, command, err := readMysqlPacket(reader)
+				// Anything but COM_QUERY, such as COM_QUIT, ends the connection
+				if err != nil || len(command) == 0 || command[0] != 0x03 {
+					return
+				}
+				for i, payload := range query(string(command[1:])) {
+					writeMysqlPacket(conn, byte(i+1), payload)
+				}
+			}
+		}(conn)
+	}
+}
+
+// Accept connections on l, and ask each one to switch to caching_sha2_password before denying it access, like MySQL 8.0
+// does for a user that doesn't exist
+func serveMysqlAuthSwitch(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+
+			writeMysqlPacket(conn, 0, mysqlHandshakePacket(mysqlTestScramble))
+			if _, _, ok := readMysqlHandshakeResponse(reader); !ok {
+				return
+			}
+			authSwitch := append([]byte{0xfe}, []byte("caching_sha2_password\x00")...)
+			writeMysqlPacket(conn, 2, append(append(authSwitch, mysqlTestScramble...), 0))
+
+			sequence, _, err := readMysqlPacket(reader)
+			if err != nil || sequence != 3 {
+				return
+			}
+			writeMysqlPacket(conn, 4, mysqlErrorPacket(1045, "28000", "Access denied for user 'health-checker'@'localhost' (using password: NO)"))
+		}(conn)
+	}
+}
+
+// The scramble that the fake servers send in their handshake
+var mysqlTestScramble = []byte("0123456789abcdefghij")
+
+// Build the handshake that MySQL 8.0.32 sends when a client connects, offering mysql_native_password
+func mysqlHandshakePacket(scramble []byte) []byte {
+	handshake := &bytes.Buffer{}
+	handshake.WriteByte(mysqlProtocolVersion)
+	handshake.WriteString("8.0.32\x00")
+	binary.Write(handshake, binary.LittleEndian, uint32(1))
+	handshake.Write(scramble[:8])
+	handshake.WriteByte(0)
+	// CLIENT_LONG_PASSWORD, CLIENT_CONNECT_WITH_DB, CLIENT_PROTOCOL_41 and CLIENT_SECURE_CONNECTION
+	binary.Write(handshake, binary.LittleEndian, uint16(0x8209))
+	handshake.WriteByte(33)
+	binary.Write(handshake, binary.LittleEndian, uint16(2))
+	// CLIENT_PLUGIN_AUTH
+	binary.Write(handshake, binary.LittleEndian, uint16(0x0008))
+	handshake.WriteByte(byte(len(scramble) + 1))
+	handshake.Write(make([]byte, 10))
+	handshake.Write(scramble[8:])
+	handshake.WriteByte(0)
+	handshake.WriteString("mysql_native_password\x00")
+	return handshake.Bytes()
+}
+
+// Read the client's reply to the handshake, skipping the capabilities, maximum packet size, character set and filler
+// to get to the user and auth response
+func readMysqlHandshakeResponse(reader io.Reader) (string, []byte, bool) {
+	_, response, err := readMysqlPacket(reader)
+	if err != nil || len(response) < 34 {
+		return "", nil, false
+	}
+	fields := bytes.SplitN(response[32:], []byte{0}, 2)
+	if len(fields) < 2 || len(fields[1]) < 1 || len(fields[1]) < 1+int(fields[1][0]) {
+		return "", nil, false
+	}
+	return string(fields[0]), fields[1][1 : 1+int(fields[1][0])], true
+}
+
+// Compute the auth response that mysql_native_password expects, which is SHA1(password) XOR
+// SHA1(scramble + SHA1(SHA1(password))), or nothing for an empty password
+func mysqlNativePassword(scramble []byte, password string) []byte {
+	if password == "" {
+		return nil
+	}
+	stage1 := sha1.Sum([]byte(password))
+	stage2 := sha1.Sum(stage1[:])
+	hash := sha1.Sum(append(append([]byte{}, scramble...), stage2[:]...))
+	for i := range hash {
+		hash[i] ^= stage1[i]
+	}
+	return hash[:]
+}
+
+func mysqlErrorPacket(code uint16, state string, message string) []byte {
+	return append([]byte{0xff, byte(code), byte(code >> 8), '#'}, []byte(state+message)...)
+}
+
+// Build the packets of a text result set with the given column names and rows, without CLIENT_DEPRECATE_EOF
+func mysqlResultSet(columns []string, rows ...[]string) [][]byte {
+	eof := []byte{0xfe, 0, 0, 2, 0}
+	packets := [][]byte{{byte(len(columns))}}
+	for _, column := range columns {
+		definition := &bytes.Buffer{}
+		for _, field := range []string{"def", "", "", "", column, ""} {
+			definition.WriteByte(byte(len(field)))
+			definition.WriteString(field)
+		}
+		// The length of the fixed fields, utf8_general_ci, a length of 255, VAR_STRING, no flags and no decimals
+		definition.Write([]byte{0x0c, 33, 0, 255, 0, 0, 0, 0xfd, 0, 0, 0, 0, 0})
+		packets = append(packets, definition.Bytes())
+	}
+	packets = append(packets, eof)
+	for _, row := range rows {
+		values := &bytes.Buffer{}
+		for _, value := range row {
+			values.WriteByte(byte(len(value)))
+			values.WriteString(value)
+		}
+		packets = append(packets, values.Bytes())
+	}
+	return append(packets, eof)
+}
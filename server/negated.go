@@ -0,0 +1,65 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// A check that is expected to fail, such as a port that must not be open. It passes when the wrapped check fails, and
+// fails when the wrapped check passes, even if the wrapped check was degraded.
+type negatedCheck struct {
+	check check
+	opts  *options.Options
+}
+
+// Checks can implement this to describe what it means for them to pass when they were expected to fail. Checks that
+// don't are reported with a generic UnexpectedlyPassed error.
+type negatable interface {
+	negatedError() error
+}
+
+// Wrap c in a negatedCheck if negate is true
+func negateIf(negate bool, c check, opts *options.Options) check {
+	if !negate {
+		return c
+	}
+	return &negatedCheck{check: c, opts: opts}
+}
+
+func (c *negatedCheck) Name() string {
+	return fmt.Sprintf("%s (negated)", c.check.Name())
+}
+
+func (c *negatedCheck) Run() *checkResult {
+	result := c.check.Run()
+	if !result.Passed() {
+		c.opts.Logger.Infof("%s failed as expected: %s", c.check.Name(), result.Err)
+		return &checkResult{Details: result.Details}
+	}
+
+	if n, ok := c.check.(negatable); ok {
+		return &checkResult{Err: n.negatedError(), Details: result.Details}
+	}
+	return &checkResult{Err: UnexpectedlyPassed(c.check.Name()), Details: result.Details}
+}
+
+// Custom error types
+
+type UnexpectedlyPassed string
+
+func (name UnexpectedlyPassed) Error() string {
+	return fmt.Sprintf("%s unexpectedly passed", string(name))
+}
+
+type PortUnexpectedlyOpen int
+
+func (port PortUnexpectedlyOpen) Error() string {
+	return fmt.Sprintf("port %d unexpectedly open", int(port))
+}
+
+type ScriptUnexpectedlySucceeded string
+
+func (script ScriptUnexpectedlySucceeded) Error() string {
+	return fmt.Sprintf("script %s unexpectedly succeeded", string(script))
+}
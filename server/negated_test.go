@@ -0,0 +1,76 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// A check that always returns the same result, for testing checks that wrap other checks
+type fixedCheck struct {
+	name   string
+	result checkResult
+}
+
+func (c *fixedCheck) Name() string {
+	return c.name
+}
+
+func (c *fixedCheck) Run() *checkResult {
+	result := c.result
+	return &result
+}
+
+func TestNegatedCheck(t *testing.T) {
+	// Will *not* run parallel because we're opening random tcp ports
+	// and want to avoid port clashes
+	ports, err := test.GetFreePorts(2)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+	openPort, closedPort := ports[0], ports[1]
+
+	l, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, openPort))
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer l.Close()
+	go handleRequests(t, l, nil)
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	testCases := []struct {
+		name        string
+		check       check
+		expectedErr string
+	}{
+		{"closed port", &tcpCheck{tcp: options.TcpCheck{Port: closedPort}, opts: opts}, ""},
+		{"open port", &tcpCheck{tcp: options.TcpCheck{Port: openPort}, opts: opts}, fmt.Sprintf("port %d unexpectedly open", openPort)},
+		{"failing script", &scriptCheck{script: options.Script{Name: "false"}, opts: opts}, ""},
+		{"succeeding script", &scriptCheck{script: options.Script{Name: "true"}, opts: opts}, "script true unexpectedly succeeded"},
+		{"failed", &fixedCheck{name: "Fixed", result: checkResult{Err: errors.New("failed")}}, ""},
+		{"passed", &fixedCheck{name: "Fixed"}, "Fixed unexpectedly passed"},
+		{"degraded", &fixedCheck{name: "Fixed", result: checkResult{Warning: errors.New("degraded")}}, "Fixed unexpectedly passed"},
+	}
+
+	for _, testCase := range testCases {
+		c := negateIf(true, testCase.check, opts)
+		assert.Equal(t, testCase.check.Name()+" (negated)", c.Name(), testCase.name)
+
+		result := c.Run()
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Equal(t, testCase.expectedErr, result.Err.Error(), testCase.name)
+		}
+		assert.Nil(t, result.Warning, testCase.name)
+	}
+
+	unchanged := &fixedCheck{name: "Fixed"}
+	assert.Equal(t, check(unchanged), negateIf(false, unchanged, opts))
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// The ICMP message types that we send and expect back
+const (
+	icmpEchoRequest   = 8
+	icmpEchoReply     = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+// The data that we send in every echo request, which the reply must echo back
+var pingPayload = []byte("health-checker")
+
+// Check that a host replies to ICMP echo requests, such as the upstream router of a gateway
+type pingCheck struct {
+	ping options.PingCheck
+	opts *options.Options
+}
+
+func (c *pingCheck) Name() string {
+	return fmt.Sprintf("Ping %s", c.ping.Host)
+}
+
+func (c *pingCheck) Run() *checkResult {
+	c.opts.Logger.Infof("Attempting to ping %s %d times...", c.ping.Host, c.ping.Count)
+
+	network := "ip"
+	if c.ping.IpVersion != 0 {
+		network = fmt.Sprintf("ip%d", c.ping.IpVersion)
+	}
+	address, err := net.ResolveIPAddr(network, c.ping.Host)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+	ipv6 := address.IP.To4() == nil
+
+	conn, err := listenIcmp(ipv6)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	defer conn.Close()
+
+	rtts, err := sendPings(conn, &net.UDPAddr{IP: address.IP, Zone: address.Zone}, ipv6, c.ping)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+	return evaluatePings(c.ping, rtts)
+}
+
+// Send the echo requests one at a time over conn, waiting for the reply to each one before sending the next, and return
+// the round trip times of the replies that arrived
+func sendPings(conn net.PacketConn, destination net.Addr, ipv6 bool, ping options.PingCheck) ([]time.Duration, error) {
+	rtts := []time.Duration{}
+	reply := make([]byte, 1500)
+
+	for seq := 1; seq <= ping.Count; seq++ {
+		if seq > 1 {
+			time.Sleep(ping.Interval)
+		}
+
+		sent := time.Now()
+		if _, err := conn.WriteTo(newEchoRequest(ipv6, seq), destination); err != nil {
+			return nil, err
+		}
+
+		conn.SetReadDeadline(sent.Add(ping.Timeout))
+		for {
+			n, _, err := conn.ReadFrom(reply)
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if isEchoReply(reply[:n], ipv6, seq) {
+				rtts = append(rtts, time.Since(sent))
+				break
+			}
+		}
+	}
+
+	return rtts, nil
+}
+
+// Compare the packet loss and the average round trip time to the thresholds of the check. A host that didn't reply at
+// all fails the check.
+func evaluatePings(ping options.PingCheck, rtts []time.Duration) *checkResult {
+	result := &checkResult{Details: map[string]string{
+		"sent":     strconv.Itoa(ping.Count),
+		"received": strconv.Itoa(len(rtts)),
+	}}
+	if len(rtts) == 0 {
+		result.Err = NoPingReplies{host: ping.Host, count: ping.Count}
+	}
+
+	lost := ping.Count - len(rtts)
+	result.checkMaximum("loss_percent", float64(lost)*100/float64(ping.Count), ping.LossPercent)
+
+	if len(rtts) > 0 {
+		var total time.Duration
+		for _, rtt := range rtts {
+			total += rtt
+		}
+		result.checkMaximum("rtt_ms", float64(total)/float64(len(rtts))/float64(time.Millisecond), ping.RttMs)
+	}
+
+	return result
+}
+
+// Build an echo request, which has a type, a code, a checksum, an identifier, a sequence number and data. The kernel
+// replaces the identifier with one of its own for unprivileged ICMP sockets, so replies are matched by sequence number.
+func newEchoRequest(ipv6 bool, seq int) []byte {
+	message := &bytes.Buffer{}
+	messageType := byte(icmpEchoRequest)
+	if ipv6 {
+		messageType = icmpv6EchoRequest
+	}
+	message.Write([]byte{messageType, 0, 0, 0})
+	binary.Write(message, binary.BigEndian, uint16(os.Getpid()))
+	binary.Write(message, binary.BigEndian, uint16(seq))
+	message.Write(pingPayload)
+
+	request := message.Bytes()
+	// The checksum of ICMPv6 covers a pseudo header with the IP addresses, so the kernel computes it for us
+	if !ipv6 {
+		binary.BigEndian.PutUint16(request[2:], icmpChecksum(request))
+	}
+	return request
+}
+
+// Return true if message is the reply to the echo request with the given sequence number
+func isEchoReply(message []byte, ipv6 bool, seq int) bool {
+	expectedType := byte(icmpEchoReply)
+	if ipv6 {
+		expectedType = icmpv6EchoReply
+	}
+	return len(message) >= 8 &&
+		message[0] == expectedType &&
+		binary.BigEndian.Uint16(message[6:]) == uint16(seq) &&
+		bytes.Equal(message[8:], pingPayload)
+}
+
+// The internet checksum from RFC 1071, which is the ones' complement of the ones' complement sum of 16 bit words
+func icmpChecksum(message []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(message); i += 2 {
+		sum += uint32(message[i])<<8 | uint32(message[i+1])
+	}
+	if len(message)%2 == 1 {
+		sum += uint32(message[len(message)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
+
+// Custom error types
+
+type NoPingReplies struct {
+	host  string
+	count int
+}
+
+func (err NoPingReplies) Error() string {
+	return fmt.Sprintf("%s did not reply to any of %d echo requests", err.host, err.count)
+}
+
+type PingNotPermitted struct {
+	gid int
+}
+
+func (err PingNotPermitted) Error() string {
+	return fmt.Sprintf("unprivileged ICMP sockets are not permitted for group %d. Allow them with: sysctl -w net.ipv4.ping_group_range=\"%d %d\"", err.gid, err.gid, err.gid)
+}
@@ -0,0 +1,39 @@
+//go:build linux
+// +build linux
+
+package server
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// Open an unprivileged ICMP socket, which Linux allows for the groups in the net.ipv4.ping_group_range sysctl. Despite
+// its name, the sysctl applies to IPv6 too.
+func listenIcmp(ipv6 bool) (net.PacketConn, error) {
+	family, protocol := syscall.AF_INET, syscall.IPPROTO_ICMP
+	var address syscall.Sockaddr = &syscall.SockaddrInet4{}
+	if ipv6 {
+		family, protocol = syscall.AF_INET6, syscall.IPPROTO_ICMPV6
+		address = &syscall.SockaddrInet6{}
+	}
+
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, protocol)
+	if err == syscall.EACCES || err == syscall.EPERM {
+		return nil, PingNotPermitted{gid: os.Getegid()}
+	}
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	if err := syscall.Bind(fd, address); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	// The file is a duplicate of the socket, so it can be closed once the connection is open
+	file := os.NewFile(uintptr(fd), "icmp")
+	defer file.Close()
+	return net.FilePacketConn(file)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package server
+
+import (
+	"errors"
+	"net"
+)
+
+func listenIcmp(ipv6 bool) (net.PacketConn, error) {
+	return nil, errors.New("ping checks are only supported on Linux")
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPingLoopback(t *testing.T) {
+	t.Parallel()
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		ping := options.PingCheck{Host: host, Count: 2, Interval: 10 * time.Millisecond, Timeout: time.Second}
+		result := (&pingCheck{ping: ping, opts: opts}).Run()
+		if _, ok := result.Err.(PingNotPermitted); ok {
+			t.Skipf("Skipping ping test: %s", result.Err)
+		}
+		if opErr, ok := result.Err.(*net.OpError); ok && host == "::1" {
+			t.Skipf("Skipping IPv6 ping test: %s", opErr)
+		}
+		if assert.Nil(t, result.Err, host) {
+			assert.Equal(t, "2", result.Details["received"], host)
+		}
+	}
+}
+
+func TestSendPings(t *testing.T) {
+	t.Parallel()
+
+	// Unprivileged ICMP sockets are datagram sockets, so a UDP socket that echoes every other request stands in for a
+	// host with 50% packet loss
+	responder, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer responder.Close()
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			n, from, err := responder.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			request := buffer[:n]
+			if request[7]%2 == 0 {
+				continue
+			}
+			reply := append([]byte{icmpEchoReply, 0, 0, 0}, request[4:]...)
+			responder.WriteTo(reply, from)
+		}
+	}()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer conn.Close()
+
+	ping := options.PingCheck{Host: "gateway", Count: 4, Interval: time.Millisecond, Timeout: time.Second}
+	rtts, err := sendPings(conn, responder.LocalAddr(), false, ping)
+	if assert.Nil(t, err) {
+		assert.Len(t, rtts, 2)
+	}
+}
+
+func TestEvaluatePings(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		ping            options.PingCheck
+		rtts            []time.Duration
+		expectedErr     string
+		expectedWarning string
+	}{
+		{
+			"all replies",
+			options.PingCheck{Host: "gateway", Count: 2, LossPercent: options.Thresholds{Fail: 50}, RttMs: options.Thresholds{Warn: 20}},
+			[]time.Duration{5 * time.Millisecond, 15 * time.Millisecond},
+			"",
+			"",
+		},
+		{
+			"slow replies",
+			options.PingCheck{Host: "gateway", Count: 2, RttMs: options.Thresholds{Warn: 20, Fail: 100}},
+			[]time.Duration{25 * time.Millisecond, 35 * time.Millisecond},
+			"",
+			"rtt_ms is 30.00, which is more than 20.00",
+		},
+		{
+			"packet loss",
+			options.PingCheck{Host: "gateway", Count: 4, LossPercent: options.Thresholds{Warn: 10, Fail: 50}},
+			[]time.Duration{time.Millisecond},
+			"loss_percent is 75.00, which is more than 50.00",
+			"loss_percent is 75.00, which is more than 10.00",
+		},
+		{
+			"no replies",
+			options.PingCheck{Host: "gateway", Count: 3},
+			[]time.Duration{},
+			"gateway did not reply to any of 3 echo requests",
+			"",
+		},
+	}
+
+	for _, testCase := range testCases {
+		result := evaluatePings(testCase.ping, testCase.rtts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Equal(t, testCase.expectedErr, result.Err.Error(), testCase.name)
+		}
+		if testCase.expectedWarning == "" {
+			assert.Nil(t, result.Warning, testCase.name)
+		} else if assert.NotNil(t, result.Warning, testCase.name) {
+			assert.Equal(t, testCase.expectedWarning, result.Warning.Error(), testCase.name)
+		}
+	}
+}
+
+func TestIcmpChecksum(t *testing.T) {
+	t.Parallel()
+
+	// A valid message sums to zero, including its checksum
+	request := newEchoRequest(false, 7)
+	assert.Equal(t, uint16(0), icmpChecksum(request))
+	assert.True(t, isEchoReply(append([]byte{icmpEchoReply, 0, 0, 0}, request[4:]...), false, 7))
+	assert.False(t, isEchoReply(append([]byte{icmpEchoReply, 0, 0, 0}, request[4:]...), false, 8))
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	_ "github.com/lib/pq"
+)
+
+// The user that is sent in the startup message when no user is configured. The server rejects it, but only once it
+// has started accepting connections, which is all we want to know.
+const postgresProbeUser = "health-checker"
+
+// The version of the PostgreSQL protocol that we speak, which is 3.0
+const postgresProtocolVersion = 3 << 16
+
+// Check that a PostgreSQL server is accepting connections, and optionally that we can log in and run a query
+type postgresCheck struct {
+	postgres options.PostgresCheck
+	opts     *options.Options
+}
+
+func (c *postgresCheck) Name() string {
+	return fmt.Sprintf("PostgreSQL check of %s", c.postgres.Address)
+}
+
+func (c *postgresCheck) Run() *checkResult {
+	if c.postgres.User == "" {
+		return attemptPostgresStartup(c.postgres, c.opts)
+	}
+	return attemptPostgresQuery(c.postgres, c.opts)
+}
+
+// Send a startup message and read the server's reply, much like pg_isready does. A server that is starting up, shutting
+// down or in crash recovery replies with an error before asking for a password, while a server that accepts
+// connections asks for a password, or rejects our user.
+func attemptPostgresStartup(postgres options.PostgresCheck, opts *options.Options) *checkResult {
+	opts.Logger.Infof("Attempting the PostgreSQL startup handshake with %s...", postgres.Address)
+
+	conn, err := net.DialTimeout("tcp", postgres.Address, defaultCheckTimeout)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(defaultCheckTimeout))
+
+	startup := &bytes.Buffer{}
+	binary.Write(startup, binary.BigEndian, int32(postgresProtocolVersion))
+	for _, parameter := range []string{"user", postgresProbeUser, "database", postgres.Database} {
+		startup.WriteString(parameter)
+		startup.WriteByte(0)
+	}
+	startup.WriteByte(0)
+	if _, err := conn.Write(append(postgresLength(startup.Len()), startup.Bytes()...)); err != nil {
+		return &checkResult{Err: err}
+	}
+
+	result := &checkResult{Details: map[string]string{}}
+	reader := bufio.NewReader(conn)
+	for {
+		messageType, body, err := readPostgresMessage(reader)
+		if err != nil {
+			return &checkResult{Err: err}
+		}
+
+		switch messageType {
+		case 'R':
+			// An authentication request. If it's anything other than AuthenticationOk, the server wants a password.
+			if len(body) < 4 || binary.BigEndian.Uint32(body) != 0 {
+				return result
+			}
+		case 'S':
+			parameter := bytes.SplitN(body, []byte{0}, 3)
+			if len(parameter) == 3 && string(parameter[0]) == "server_version" {
+				result.Details["version"] = string(parameter[1])
+			}
+		case 'Z':
+			// The server trusted us and is ready for a query
+			conn.Write([]byte{'X', 0, 0, 0, 4})
+			return result
+		case 'E':
+			postgresErr := parsePostgresError(body)
+			// Errors about our user, password or database mean the server is accepting connections
+			if strings.HasPrefix(postgresErr.code, "28") || postgresErr.code == "3D000" {
+				return result
+			}
+			return &checkResult{Err: postgresErr}
+		}
+	}
+}
+
+// Log in with the configured user and query the server's version and whether it is in recovery, which means it is a
+// replica
+func attemptPostgresQuery(postgres options.PostgresCheck, opts *options.Options) *checkResult {
+	opts.Logger.Infof("Attempting to log in to PostgreSQL at %s as %s...", postgres.Address, postgres.User)
+
+	// libpq takes connect_timeout in whole seconds
+	connectTimeout := strconv.Itoa(int(defaultCheckTimeout / time.Second))
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(postgres.User, postgres.Password),
+		Host:     postgres.Address,
+		Path:     "/" + postgres.Database,
+		RawQuery: url.Values{"sslmode": {postgres.SslMode}, "connect_timeout": {connectTimeout}}.Encode(),
+	}
+	db, err := sql.Open("postgres", dsn.String())
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultCheckTimeout)
+	defer cancel()
+
+	var version string
+	var inRecovery bool
+	if err := db.QueryRowContext(ctx, "SELECT current_setting('server_version'), pg_is_in_recovery()").Scan(&version, &inRecovery); err != nil {
+		return &checkResult{Err: err}
+	}
+
+	role := "primary"
+	if inRecovery {
+		role = "replica"
+	}
+	return checkDatabaseRole(postgres.Roles, version, role)
+}
+
+// Report the version and role of a database, failing if the role isn't one of the expected roles
+func checkDatabaseRole(roles []string, version string, role string) *checkResult {
+	result := &checkResult{Details: map[string]string{"version": version, "role": role}}
+	if len(roles) > 0 && !containsString(roles, role) {
+		result.Err = UnexpectedDatabaseRole{expected: roles, actual: role}
+	}
+	return result
+}
+
+// Read a message, which is a type byte followed by the length of the message, including the length itself, and its body
+func readPostgresMessage(reader io.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, nil, err
+	}
+
+	length := int(binary.BigEndian.Uint32(header[1:])) - 4
+	if length < 0 || length > maxResponseSize {
+		return 0, nil, UnexpectedPostgresMessage(header[0])
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header[0], body, nil
+}
+
+// Parse the fields of an ErrorResponse, each of which is a type byte followed by a null terminated string
+func parsePostgresError(body []byte) PostgresError {
+	postgresErr := PostgresError{}
+	for _, field := range bytes.Split(body, []byte{0}) {
+		if len(field) < 2 {
+			continue
+		}
+		switch field[0] {
+		case 'C':
+			postgresErr.code = string(field[1:])
+		case 'M':
+			postgresErr.message = string(field[1:])
+		}
+	}
+	return postgresErr
+}
+
+func postgresLength(bodyLength int) []byte {
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(bodyLength+4))
+	return length
+}
+
+// Custom error types
+
+type PostgresError struct {
+	code    string
+	message string
+}
+
+func (err PostgresError) Error() string {
+	return fmt.Sprintf("PostgreSQL is not accepting connections: %s (SQLSTATE %s)", err.message, err.code)
+}
+
+type UnexpectedPostgresMessage byte
+
+func (messageType UnexpectedPostgresMessage) Error() string {
+	return fmt.Sprintf("received a PostgreSQL message of type %q with an invalid length", byte(messageType))
+}
+
+type UnexpectedDatabaseRole struct {
+	expected []string
+	actual   string
+}
+
+func (err UnexpectedDatabaseRole) Error() string {
+	return fmt.Sprintf("the server is a %s, expected %s", err.actual, strings.Join(err.expected, " or "))
+}
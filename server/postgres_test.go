@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptPostgresCheck(t *testing.T) {
+	// Will *not* run parallel because we're opening random tcp ports
+	// and want to avoid port clashes
+	ports, err := test.GetFreePorts(2)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+	replicaAddress := test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0])
+	startingAddress := test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[1])
+
+	// A replica that trusts the health-checker user, and asks everyone else for a password
+	replica, err := net.Listen("tcp", replicaAddress)
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer replica.Close()
+	go servePostgres(replica, func(conn net.Conn, reader *bufio.Reader, parameters map[string]string) {
+		if parameters["user"] != postgresProbeUser {
+			writePostgresMessage(conn, 'R', []byte{0, 0, 0, 3})
+			_, password, err := readPostgresMessage(reader)
+			if err != nil {
+				return
+			}
+			if string(password) != "s3cr3t\x00" {
+				writePostgresMessage(conn, 'E', []byte("SFATAL\x00C28P01\x00Mpassword authentication failed for user \"health\"\x00\x00"))
+				return
+			}
+		}
+
+		writePostgresMessage(conn, 'R', []byte{0, 0, 0, 0})
+		writePostgresMessage(conn, 'S', []byte("server_version\x0014.5\x00"))
+		writePostgresMessage(conn, 'Z', []byte{'I'})
+
+		for {
+			messageType, _, err := readPostgresMessage(reader)
+			if err != nil || messageType != 'Q' {
+				return
+			}
+			writePostgresMessage(conn, 'T', postgresRowDescription("current_setting", 25, "pg_is_in_recovery", 16))
+			writePostgresMessage(conn, 'D', postgresDataRow("14.5", "t"))
+			writePostgresMessage(conn, 'C', []byte("SELECT 1\x00"))
+			writePostgresMessage(conn, 'Z', []byte{'I'})
+		}
+	})
+
+	// A server that is still replaying its WAL after a crash
+	starting, err := net.Listen("tcp", startingAddress)
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer starting.Close()
+	go servePostgres(starting, func(conn net.Conn, reader *bufio.Reader, parameters map[string]string) {
+		writePostgresMessage(conn, 'E', []byte("SFATAL\x00C57P03\x00Mthe database system is starting up\x00\x00"))
+	})
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	testCases := []struct {
+		name            string
+		postgres        options.PostgresCheck
+		expectedErr     string
+		expectedDetails map[string]string
+	}{
+		{
+			"startup",
+			options.PostgresCheck{Address: replicaAddress, Database: "postgres"},
+			"",
+			map[string]string{"version": "14.5"},
+		},
+		{
+			"starting up",
+			options.PostgresCheck{Address: startingAddress, Database: "postgres"},
+			"the database system is starting up (SQLSTATE 57P03)",
+			nil,
+		},
+		{
+			"log in",
+			options.PostgresCheck{Address: replicaAddress, User: "health", Password: "s3cr3t", Database: "postgres", SslMode: "disable"},
+			"",
+			map[string]string{"version": "14.5", "role": "replica"},
+		},
+		{
+			"replica",
+			options.PostgresCheck{Address: replicaAddress, User: "health", Password: "s3cr3t", Database: "postgres", SslMode: "disable", Roles: []string{"replica"}},
+			"",
+			map[string]string{"version": "14.5", "role": "replica"},
+		},
+		{
+			"not a primary",
+			options.PostgresCheck{Address: replicaAddress, User: "health", Password: "s3cr3t", Database: "postgres", SslMode: "disable", Roles: []string{"primary"}},
+			"the server is a replica, expected primary",
+			nil,
+		},
+		{
+			"wrong password",
+			options.PostgresCheck{Address: replicaAddress, User: "health", Password: "wrong", Database: "postgres", SslMode: "disable"},
+			"password authentication failed",
+			nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		result := (&postgresCheck{postgres: testCase.postgres, opts: opts}).Run()
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+			assert.Equal(t, testCase.expectedDetails, result.Details, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+// Accept connections on l, read the startup message of each one and pass its parameters to handle
+func servePostgres(l net.Listener, handle func(conn net.Conn, reader *bufio.Reader, parameters map[string]string)) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+
+			length := make([]byte, 4)
+			if _, err := io.ReadFull(reader, length); err != nil {
+				return
+			}
+			startup := make([]byte, binary.BigEndian.Uint32(length)-4)
+			if _, err := io.ReadFull(reader, startup); err != nil {
+				return
+			}
+
+			parameters := map[string]string{}
+			fields := bytes.Split(startup[4:], []byte{0})
+			for i := 0; i+1 < len(fields); i += 2 {
+				parameters[string(fields[i])] = string(fields[i+1])
+			}
+			handle(conn, reader, parameters)
+		}(conn)
+	}
+}
+
+func writePostgresMessage(conn net.Conn, messageType byte, body []byte) {
+	conn.Write(append(append([]byte{messageType}, postgresLength(len(body))...), body...))
+}
+
+// Build a RowDescription for text columns with the given names and type oids
+func postgresRowDescription(nameAndOids ...interface{}) []byte {
+	body := &bytes.Buffer{}
+	binary.Write(body, binary.BigEndian, int16(len(nameAndOids)/2))
+	for i := 0; i < len(nameAndOids); i += 2 {
+		body.WriteString(nameAndOids[i].(string))
+		body.WriteByte(0)
+		binary.Write(body, binary.BigEndian, int32(0))
+		binary.Write(body, binary.BigEndian, int16(0))
+		binary.Write(body, binary.BigEndian, int32(nameAndOids[i+1].(int)))
+		binary.Write(body, binary.BigEndian, int16(-1))
+		binary.Write(body, binary.BigEndian, int32(-1))
+		binary.Write(body, binary.BigEndian, int16(0))
+	}
+	return body.Bytes()
+}
+
+func postgresDataRow(values ...string) []byte {
+	body := &bytes.Buffer{}
+	binary.Write(body, binary.BigEndian, int16(len(values)))
+	for _, value := range values {
+		binary.Write(body, binary.BigEndian, int32(len(value)))
+		body.WriteString(value)
+	}
+	return body.Bytes()
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// The kernel reports process start times in clock ticks of USER_HZ, which getconf CLK_TCK reports. We can't ask for it
+// without cgo, so we assume 100, which is what the kernel uses for USER_HZ on every architecture it supports,
+// regardless of the HZ it was built with.
+const clockTicksPerSecond = 100
+
+// What we know about a running process from /proc
+type process struct {
+	Pid     int
+	Name    string
+	Cmdline string
+	State   string
+	Uptime  time.Duration
+}
+
+func (p *process) Zombie() bool {
+	return p.State == "Z"
+}
+
+// Check that the expected number of processes matching a pidfile, name or command line are running
+type processCheck struct {
+	process options.ProcessCheck
+	opts    *options.Options
+}
+
+func (c *processCheck) Name() string {
+	return fmt.Sprintf("Processes %s", c.process.Matcher)
+}
+
+func (c *processCheck) Run() *checkResult {
+	c.opts.Logger.Infof("Attempting to find processes %s in %s...", c.process.Matcher, c.opts.ProcRoot)
+
+	processes, err := findProcesses(c.opts.ProcRoot, c.process.Matcher)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	pids := []string{}
+	for _, p := range processes {
+		pids = append(pids, strconv.Itoa(p.Pid))
+	}
+	result := &checkResult{Details: map[string]string{"pids": strings.Join(pids, ",")}}
+
+	result.Err = evaluateProcesses(c.process, processes)
+	return result
+}
+
+func evaluateProcesses(check options.ProcessCheck, processes []*process) error {
+	if len(processes) < check.Min {
+		return UnexpectedProcessCount(fmt.Sprintf("found %d matching processes, expected at least %d", len(processes), check.Min))
+	}
+	if check.Max > 0 && len(processes) > check.Max {
+		return UnexpectedProcessCount(fmt.Sprintf("found %d matching processes, expected at most %d", len(processes), check.Max))
+	}
+
+	for _, p := range processes {
+		if check.NoZombies && p.Zombie() {
+			return ProcessNotHealthy(fmt.Sprintf("process %d is a zombie", p.Pid))
+		}
+		if check.MinUptime > 0 && p.Uptime < check.MinUptime {
+			return ProcessNotHealthy(fmt.Sprintf("process %d has only been running for %s, expected at least %s", p.Pid, p.Uptime.Round(time.Second), check.MinUptime))
+		}
+	}
+
+	return nil
+}
+
+// Find the processes that match the given matcher. If the matcher has a pidfile, only the process with that pid is
+// considered. Otherwise, every process in procRoot is, except health-checker itself and its parent, whose command lines
+// contain the matcher and so would always match it.
+func findProcesses(procRoot string, matcher options.ProcessMatcher) ([]*process, error) {
+	pids := []int{}
+
+	if matcher.Pidfile != "" {
+		pid, err := readPidfile(matcher.Pidfile)
+		if err != nil {
+			return nil, err
+		}
+		pids = append(pids, pid)
+	} else {
+		entries, err := ioutil.ReadDir(procRoot)
+		if err != nil {
+			return nil, err
+		}
+		ownPids := readOwnPids(procRoot)
+		for _, entry := range entries {
+			if pid, err := strconv.Atoi(entry.Name()); err == nil && entry.IsDir() && !ownPids[pid] {
+				pids = append(pids, pid)
+			}
+		}
+	}
+
+	uptime, err := readSystemUptime(procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	processes := []*process{}
+	for _, pid := range pids {
+		p, err := readProcess(procRoot, pid, uptime)
+		if err != nil {
+			// The process may have exited since we listed it
+			continue
+		}
+		if matcher.Name != "" && p.Name != matcher.Name {
+			continue
+		}
+		if matcher.Cmdline != nil && !matcher.Cmdline.MatchString(p.Cmdline) {
+			continue
+		}
+		processes = append(processes, p)
+	}
+
+	sort.Slice(processes, func(i, j int) bool { return processes[i].Pid < processes[j].Pid })
+	return processes, nil
+}
+
+// Read the name, command line, state and uptime of a process from /proc/<pid>
+func readProcess(procRoot string, pid int, systemUptime time.Duration) (*process, error) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+
+	stat, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The name in the second field is in parentheses and may itself contain spaces and parentheses, so parse the
+	// fields that follow the last closing parenthesis
+	statString := string(stat)
+	nameEnd := strings.LastIndex(statString, ")")
+	nameStart := strings.Index(statString, "(")
+	if nameStart < 0 || nameEnd < nameStart {
+		return nil, UnexpectedProcFormat(filepath.Join(dir, "stat"))
+	}
+	fields := strings.Fields(statString[nameEnd+1:])
+	// starttime is field 22 of stat, and fields starts at field 3
+	if len(fields) < 20 {
+		return nil, UnexpectedProcFormat(filepath.Join(dir, "stat"))
+	}
+	startTicks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return nil, UnexpectedProcFormat(filepath.Join(dir, "stat"))
+	}
+	startTime := time.Duration(startTicks) * time.Second / clockTicksPerSecond
+
+	cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &process{
+		Pid:     pid,
+		Name:    statString[nameStart+1 : nameEnd],
+		Cmdline: strings.TrimSpace(strings.Replace(string(cmdline), "\x00", " ", -1)),
+		State:   fields[0],
+		Uptime:  systemUptime - startTime,
+	}, nil
+}
+
+// Return the pids of health-checker and its parent, such as a shell that started it, as seen in procRoot. We follow the
+// self link rather than calling os.Getpid, since procRoot may belong to another pid namespace, such as the host's /proc
+// mounted into a container. If there is no self link, there is nothing to skip.
+func readOwnPids(procRoot string) map[int]bool {
+	ownPids := map[int]bool{}
+
+	self, err := os.Readlink(filepath.Join(procRoot, "self"))
+	if err != nil {
+		return ownPids
+	}
+	pid, err := strconv.Atoi(filepath.Base(self))
+	if err != nil {
+		return ownPids
+	}
+	ownPids[pid] = true
+
+	// The parent pid is the second field after the name in stat
+	stat, err := ioutil.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return ownPids
+	}
+	statString := string(stat)
+	fields := strings.Fields(statString[strings.LastIndex(statString, ")")+1:])
+	if len(fields) > 1 {
+		if ppid, err := strconv.Atoi(fields[1]); err == nil {
+			ownPids[ppid] = true
+		}
+	}
+	return ownPids
+}
+
+// Read how long the system has been up from the first field of /proc/uptime
+func readSystemUptime(procRoot string) (time.Duration, error) {
+	path := filepath.Join(procRoot, "uptime")
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(contents))
+	if len(fields) == 0 {
+		return 0, UnexpectedProcFormat(path)
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, UnexpectedProcFormat(path)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func readPidfile(path string) (int, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+	if err != nil {
+		return 0, InvalidPidfile(path)
+	}
+	return pid, nil
+}
+
+// Custom error types
+
+type UnexpectedProcessCount string
+
+func (reason UnexpectedProcessCount) Error() string {
+	return string(reason)
+}
+
+type ProcessNotHealthy string
+
+func (reason ProcessNotHealthy) Error() string {
+	return string(reason)
+}
+
+type InvalidPidfile string
+
+func (path InvalidPidfile) Error() string {
+	return fmt.Sprintf("pidfile %s does not contain a pid", string(path))
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindProcesses(t *testing.T) {
+	t.Parallel()
+
+	processes, err := findProcesses(testProcRoot, options.ProcessMatcher{Pidfile: "testdata/kafka.pid"})
+	if assert.Nil(t, err) && assert.Len(t, processes, 1) {
+		assert.Equal(t, &process{
+			Pid:     100,
+			Name:    "java",
+			Cmdline: "java -Xmx1g kafka.Kafka /etc/kafka/server.properties",
+			State:   "S",
+			Uptime:  900 * time.Second,
+		}, processes[0])
+	}
+
+	processes, err = findProcesses(testProcRoot, options.ProcessMatcher{Name: "my (weird) app"})
+	if assert.Nil(t, err) && assert.Len(t, processes, 1) {
+		assert.Equal(t, 103, processes[0].Pid)
+	}
+
+	_, err = findProcesses(testProcRoot, options.ProcessMatcher{Pidfile: "testdata/invalid.pid"})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "pidfile testdata/invalid.pid does not contain a pid", err.Error())
+	}
+}
+
+func TestProcessCheck(t *testing.T) {
+	t.Parallel()
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+	opts.ProcRoot = testProcRoot
+
+	// The fixtures have a kafka process that has been running for 900s, and two worker processes, one of which is a
+	// zombie that has been running for 100s and one that has been running for 10s
+	testCases := []struct {
+		name        string
+		process     options.ProcessCheck
+		expectedErr string
+	}{
+		{
+			"pidfile",
+			options.ProcessCheck{Matcher: options.ProcessMatcher{Pidfile: "testdata/kafka.pid"}, Min: 1},
+			"",
+		},
+		{
+			"missing pidfile",
+			options.ProcessCheck{Matcher: options.ProcessMatcher{Pidfile: "testdata/missing.pid"}, Min: 1},
+			"no such file or directory",
+		},
+		{
+			"pidfile and cmdline",
+			options.ProcessCheck{Matcher: options.ProcessMatcher{Pidfile: "testdata/kafka.pid", Cmdline: regexp.MustCompile(`zookeeper`)}, Min: 1},
+			"found 0 matching processes, expected at least 1",
+		},
+		{
+			"cmdline",
+			options.ProcessCheck{Matcher: options.ProcessMatcher{Cmdline: regexp.MustCompile(`kafka\.Kafka`)}, Min: 1, Max: 1},
+			"",
+		},
+		{
+			"name with min and max",
+			options.ProcessCheck{Matcher: options.ProcessMatcher{Name: "worker"}, Min: 2, Max: 2},
+			"",
+		},
+		{
+			"too few",
+			options.ProcessCheck{Matcher: options.ProcessMatcher{Name: "worker"}, Min: 3},
+			"found 2 matching processes, expected at least 3",
+		},
+		{
+			"too many",
+			options.ProcessCheck{Matcher: options.ProcessMatcher{Name: "worker"}, Min: 1, Max: 1},
+			"found 2 matching processes, expected at most 1",
+		},
+		{
+			"zombie",
+			options.ProcessCheck{Matcher: options.ProcessMatcher{Name: "worker"}, Min: 1, NoZombies: true},
+			"process 101 is a zombie",
+		},
+		{
+			"min uptime",
+			options.ProcessCheck{Matcher: options.ProcessMatcher{Cmdline: regexp.MustCompile(`kafka`)}, Min: 1, MinUptime: time.Minute},
+			"",
+		},
+		{
+			"recently restarted",
+			options.ProcessCheck{Matcher: options.ProcessMatcher{Cmdline: regexp.MustCompile(`--queue`)}, Min: 1, MinUptime: time.Minute},
+			"process 102 has only been running for 10s, expected at least 1m0s",
+		},
+	}
+
+	for _, testCase := range testCases {
+		result := (&processCheck{process: testCase.process, opts: opts}).Run()
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestFindProcessesSkipsHealthChecker(t *testing.T) {
+	t.Parallel()
+
+	// health-checker runs as pid 200, started by a shell with pid 199. Both have the matcher on their command lines, but
+	// only pid 300 is the process we're looking for.
+	procRoot, err := ioutil.TempDir("", "health-checker-proc-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(procRoot)
+
+	writeFakeProcess(t, procRoot, 199, 1, "sh", "sh\x00-c\x00health-checker --process cmdline=redis-server")
+	writeFakeProcess(t, procRoot, 200, 199, "health-checker", "health-checker\x00--process\x00cmdline=redis-server")
+	writeFakeProcess(t, procRoot, 300, 1, "redis-server", "redis-server\x00*:6379")
+	if err := ioutil.WriteFile(filepath.Join(procRoot, "uptime"), []byte("1000.00 900.00\n"), 0644); err != nil {
+		assert.FailNow(t, "Failed to write uptime: %v", err.Error())
+	}
+	if err := os.Symlink("200", filepath.Join(procRoot, "self")); err != nil {
+		assert.FailNow(t, "Failed to create self link: %v", err.Error())
+	}
+
+	processes, err := findProcesses(procRoot, options.ProcessMatcher{Cmdline: regexp.MustCompile(`redis-server`)})
+	if assert.Nil(t, err) && assert.Len(t, processes, 1) {
+		assert.Equal(t, 300, processes[0].Pid)
+	}
+
+	// The same goes for the real /proc, where the test binary is the only process with its own path on its command line
+	if _, err := os.Stat("/proc/self/stat"); err == nil {
+		processes, err := findProcesses("/proc", options.ProcessMatcher{Cmdline: regexp.MustCompile(regexp.QuoteMeta(os.Args[0]))})
+		if assert.Nil(t, err) {
+			assert.Empty(t, processes)
+		}
+	}
+}
+
+func writeFakeProcess(t *testing.T, procRoot string, pid int, ppid int, name string, cmdline string) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		assert.FailNow(t, "Failed to create process dir: %v", err.Error())
+	}
+	stat := fmt.Sprintf("%d (%s) S %d %d %d 0 -1 4194560 100 0 0 0 10 5 0 0 20 0 1 0 10000 1000000 25\n", pid, name, ppid, pid, pid)
+	if err := ioutil.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644); err != nil {
+		assert.FailNow(t, "Failed to write stat: %v", err.Error())
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline+"\x00"), 0644); err != nil {
+		assert.FailNow(t, "Failed to write cmdline: %v", err.Error())
+	}
+}
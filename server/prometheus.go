@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// Metrics endpoints can be much larger than a health document, so we read up to this much of them
+const maxMetricsBodySize = 16 * 1024 * 1024
+
+// Check the values of series scraped from a Prometheus metrics endpoint
+type prometheusCheck struct {
+	prometheus options.PrometheusCheck
+	opts       *options.Options
+}
+
+func (c *prometheusCheck) Name() string {
+	return fmt.Sprintf("Prometheus metrics at %s", c.prometheus.Url)
+}
+
+func (c *prometheusCheck) Run() *checkResult {
+	c.opts.Logger.Infof("Attempting to scrape metrics from %s...", c.prometheus.Url)
+
+	request, err := http.NewRequest(http.MethodGet, c.prometheus.Url, nil)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+	// Ask for the text format, since some endpoints serve the protobuf format by default
+	request.Header.Set("Accept", "text/plain;version=0.0.4")
+
+	response, err := newHttpClient(c.prometheus.UnixSocket, c.prometheus.Timeout).Do(request)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	defer response.Body.Close()
+	// Read whatever is left of the body, such as after an invalid line, so that the next scrape can reuse the connection
+	defer io.Copy(ioutil.Discard, io.LimitReader(response.Body, maxMetricsBodySize))
+
+	if response.StatusCode != http.StatusOK {
+		return &checkResult{Err: UnexpectedHttpStatus(response.StatusCode)}
+	}
+	return evaluateMetrics(io.LimitReader(response.Body, maxMetricsBodySize), c.prometheus.Assertions)
+}
+
+// Read the series in the text exposition format from reader and evaluate the assertions against them. Every series that
+// an assertion selects must satisfy it, and at least one series must be selected. The value of each selected series is
+// reported in the details of the result.
+func evaluateMetrics(reader io.Reader, assertions []options.MetricAssertion) *checkResult {
+	result := &checkResult{Details: map[string]string{}}
+
+	// Keep only the series that the assertions select or refer to, and evaluate them once the scrape has been read,
+	// since a series that is referred to may come after the series it is compared with
+	samples := []metricSample{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxHttpBodySize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, labels, rest, err := options.ParseMetricSeries(line)
+		if err != nil {
+			return &checkResult{Err: InvalidMetricLine{line: line, reason: err.Error()}}
+		}
+		// The value may be followed by a timestamp
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return &checkResult{Err: InvalidMetricLine{line: line, reason: "missing value"}}
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return &checkResult{Err: InvalidMetricLine{line: line, reason: fmt.Sprintf("invalid value %s", fields[0])}}
+		}
+
+		sample := metricSample{series: options.MetricSeries{Name: name, Labels: labels}, text: fields[0], value: value}
+		if sample.neededBy(assertions) {
+			samples = append(samples, sample)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return &checkResult{Err: err}
+	}
+
+	for _, assertion := range assertions {
+		expected := assertion.Value
+		if assertion.Reference != nil {
+			reference, found := findMetricSample(samples, *assertion.Reference)
+			if !found {
+				result.recordMetricFailure(assertion, MetricNotFound{assertion: assertion, series: *assertion.Reference})
+				continue
+			}
+			result.Details[reference.series.String()] = reference.text
+			expected = reference.value
+		}
+
+		selected := false
+		for _, sample := range samples {
+			if !assertion.Selects(sample.series.Name, sample.series.Labels) {
+				continue
+			}
+			selected = true
+
+			series := sample.series.String()
+			result.Details[series] = sample.text
+			if !options.CompareNumbers(sample.value, assertion.Operator, expected) {
+				result.recordMetricFailure(assertion, MetricAssertionFailed{assertion: assertion, series: series, value: sample.text})
+			}
+		}
+		if !selected {
+			result.recordMetricFailure(assertion, MetricNotFound{assertion: assertion, series: options.MetricSeries{Name: assertion.Name, Labels: assertion.Labels}})
+		}
+	}
+	return result
+}
+
+// A series read from a scrape, with its value both as written and as a number
+type metricSample struct {
+	series options.MetricSeries
+	text   string
+	value  float64
+}
+
+// Return true if any of the assertions selects the sample or refers to it
+func (sample metricSample) neededBy(assertions []options.MetricAssertion) bool {
+	for _, assertion := range assertions {
+		if assertion.Selects(sample.series.Name, sample.series.Labels) {
+			return true
+		}
+		if assertion.Reference != nil && assertion.Reference.Selects(sample.series.Name, sample.series.Labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the first sample that the series selects
+func findMetricSample(samples []metricSample, series options.MetricSeries) (metricSample, bool) {
+	for _, sample := range samples {
+		if series.Selects(sample.series.Name, sample.series.Labels) {
+			return sample, true
+		}
+	}
+	return metricSample{}, false
+}
+
+// Record the first failed assertion as the error, or as the warning if the assertion only degrades the check
+func (result *checkResult) recordMetricFailure(assertion options.MetricAssertion, failure error) {
+	switch {
+	case assertion.Warn && result.Warning == nil:
+		result.Warning = failure
+	case !assertion.Warn && result.Err == nil:
+		result.Err = failure
+	}
+}
+
+// Custom error types
+
+type InvalidMetricLine struct {
+	line   string
+	reason string
+}
+
+func (err InvalidMetricLine) Error() string {
+	return fmt.Sprintf("invalid line in metrics %q: %s", err.line, err.reason)
+}
+
+type MetricAssertionFailed struct {
+	assertion options.MetricAssertion
+	series    string
+	value     string
+}
+
+func (err MetricAssertionFailed) Error() string {
+	return fmt.Sprintf("expected %s but %s is %s", err.assertion, err.series, err.value)
+}
+
+type MetricNotFound struct {
+	assertion options.MetricAssertion
+	series    options.MetricSeries
+}
+
+func (err MetricNotFound) Error() string {
+	return fmt.Sprintf("expected %s but no series matches %s", err.assertion, err.series)
+}
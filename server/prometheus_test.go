@@ -0,0 +1,137 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMetrics = `# HELP up Whether the app is up.
+# TYPE up gauge
+up 1
+# HELP queue_depth The number of messages waiting in each queue.
+# TYPE queue_depth gauge
+queue_depth{queue="emails",region="us-east-1"} 120
+queue_depth{queue="emails",region="eu-west-1"} 1500
+queue_depth{queue="sms \"urgent\", retries",region="us-east-1"} 3 1690000000000
+http_request_duration_seconds_bucket{le="+Inf"} 42
+process_open_fds 120
+process_max_fds 1024
+`
+
+func TestPrometheusCheck(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metrics":
+			fmt.Fprint(w, testMetrics)
+		case "/invalid":
+			fmt.Fprint(w, "up one\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name            string
+		path            string
+		assertions      []string
+		warnAssertions  []string
+		expectedErr     string
+		expectedWarning string
+	}{
+		{"up", "/metrics", []string{"up == 1"}, nil, "", ""},
+		{"labels", "/metrics", []string{`queue_depth{region="us-east-1",queue="emails"} < 1000`}, nil, "", ""},
+		{"escaped labels", "/metrics", []string{`queue_depth{queue="sms \"urgent\", retries"} <= 3`}, nil, "", ""},
+		{"every series", "/metrics", []string{`queue_depth{queue="emails"} < 1000`}, nil, `expected queue_depth{queue="emails"} < 1000 but queue_depth{queue="emails",region="eu-west-1"} is 1500`, ""},
+		{"warning", "/metrics", []string{"up == 1"}, []string{`queue_depth{queue="emails"} < 1000`}, "", `expected queue_depth{queue="emails"} < 1000 but queue_depth{queue="emails",region="eu-west-1"} is 1500`},
+		{"infinite bucket", "/metrics", []string{`http_request_duration_seconds_bucket{le="+Inf"} > 0`}, nil, "", ""},
+		{"missing series", "/metrics", []string{`up{job="worker"} == 1`}, nil, `expected up{job="worker"} == 1 but no series matches up{job="worker"}`, ""},
+		{"reference", "/metrics", []string{"process_open_fds < ${process_max_fds}"}, nil, "", ""},
+		{"reference with labels", "/metrics", []string{`queue_depth{region="eu-west-1"} > ${queue_depth{queue="emails",region="us-east-1"}}`}, nil, "", ""},
+		{"reference does not hold", "/metrics", []string{"process_max_fds < ${process_open_fds}"}, nil, "expected process_max_fds < ${process_open_fds} but process_max_fds is 1024", ""},
+		{"missing reference", "/metrics", []string{"process_open_fds < ${process_fds_limit}"}, nil, "expected process_open_fds < ${process_fds_limit} but no series matches process_fds_limit", ""},
+		{"invalid metrics", "/invalid", []string{"up == 1"}, nil, `invalid line in metrics "up one": invalid value one`, ""},
+		{"not found", "/missing", []string{"up == 1"}, nil, "unexpected HTTP status 404 Not Found", ""},
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	for _, testCase := range testCases {
+		check := options.PrometheusCheck{Url: server.URL + testCase.path, Timeout: defaultCheckTimeout}
+		for _, s := range testCase.assertions {
+			assertion, err := options.ParseMetricAssertion(s, false)
+			assert.Nil(t, err, testCase.name)
+			check.Assertions = append(check.Assertions, assertion)
+		}
+		for _, s := range testCase.warnAssertions {
+			assertion, err := options.ParseMetricAssertion(s, true)
+			assert.Nil(t, err, testCase.name)
+			check.Assertions = append(check.Assertions, assertion)
+		}
+
+		result := (&prometheusCheck{prometheus: check, opts: opts}).Run()
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Equal(t, testCase.expectedErr, result.Err.Error(), testCase.name)
+		}
+		if testCase.expectedWarning == "" {
+			assert.Nil(t, result.Warning, testCase.name)
+		} else if assert.NotNil(t, result.Warning, testCase.name) {
+			assert.Equal(t, testCase.expectedWarning, result.Warning.Error(), testCase.name)
+		}
+	}
+}
+
+func TestPrometheusCheckOverUnixSocketReusesConnection(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "health-checker-prometheus-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "metrics.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer l.Close()
+
+	// The invalid line comes first, so that the check stops reading long before the end of the body
+	connections := make(chan struct{}, 100)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "up one\n"+strings.Repeat(testMetrics, 10000))
+		}),
+		ConnState: func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				connections <- struct{}{}
+			}
+		},
+	}
+	go server.Serve(l)
+
+	assertion, err := options.ParseMetricAssertion("up == 1", false)
+	assert.Nil(t, err)
+	check := options.PrometheusCheck{Url: "http://localhost/metrics", UnixSocket: path, Timeout: defaultCheckTimeout, Assertions: []options.MetricAssertion{assertion}}
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	for i := 0; i < 20; i++ {
+		assert.NotNil(t, (&prometheusCheck{prometheus: check, opts: opts}).Run().Err)
+	}
+	assert.Equal(t, 1, len(connections))
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// Check that at least a minimum number of a group of checks pass. The group is degraded if any of its checks fail
+// without breaking the quorum.
+type quorumCheck struct {
+	quorum options.QuorumCheck
+	opts   *options.Options
+}
+
+func (c *quorumCheck) Name() string {
+	return fmt.Sprintf("Quorum %s", c.quorum.Name)
+}
+
+func (c *quorumCheck) Run() *checkResult {
+	children := []check{}
+	for _, port := range c.quorum.Ports {
+		children = append(children, &tcpCheck{tcp: options.TcpCheck{Port: port}, opts: c.opts})
+	}
+	for _, script := range c.quorum.Scripts {
+		children = append(children, &scriptCheck{script: script, opts: c.opts})
+	}
+
+	results := make([]*checkResult, len(children))
+	var waitGroup = sync.WaitGroup{}
+	for i, child := range children {
+		waitGroup.Add(1)
+		go func(i int, child check) {
+			defer waitGroup.Done()
+			results[i] = child.Run()
+		}(i, child)
+	}
+	waitGroup.Wait()
+
+	failed := []string{}
+	for i, result := range results {
+		if !result.Passed() {
+			failed = append(failed, fmt.Sprintf("%s (%s)", children[i].Name(), result.Err))
+		}
+	}
+
+	passed := len(children) - len(failed)
+	required := c.quorum.Required()
+	result := &checkResult{Details: map[string]string{
+		"passed":   strconv.Itoa(passed),
+		"total":    strconv.Itoa(len(children)),
+		"required": strconv.Itoa(required),
+	}}
+
+	if passed < required {
+		result.Err = QuorumNotReached{passed: passed, total: len(children), required: required, failed: failed}
+	} else if len(failed) > 0 {
+		result.Warning = QuorumChecksFailed(failed)
+	}
+	return result
+}
+
+// Custom error types
+
+type QuorumNotReached struct {
+	passed   int
+	total    int
+	required int
+	failed   []string
+}
+
+func (err QuorumNotReached) Error() string {
+	return fmt.Sprintf("only %d of %d checks passed, expected at least %d. Failed: %s", err.passed, err.total, err.required, strings.Join(err.failed, ", "))
+}
+
+type QuorumChecksFailed []string
+
+func (failed QuorumChecksFailed) Error() string {
+	return fmt.Sprintf("%d checks failed without breaking the quorum: %s", len(failed), strings.Join(failed, ", "))
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuorumCheck(t *testing.T) {
+	// Will *not* run parallel because we're opening random tcp ports
+	// and want to avoid port clashes
+	ports, err := test.GetFreePorts(4)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+
+	// Only the first three ports are listening
+	for _, port := range ports[:3] {
+		l, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, port))
+		if err != nil {
+			assert.FailNow(t, "Failed to start listening: %s", err.Error())
+		}
+		defer l.Close()
+		go handleRequests(t, l, nil)
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+	closedPort := fmt.Sprintf("TCP connection to port %d", ports[3])
+
+	testCases := []struct {
+		name            string
+		quorum          options.QuorumCheck
+		expectedErr     string
+		expectedWarning string
+	}{
+		{
+			"all pass",
+			options.QuorumCheck{Ports: ports[:3], Min: 3},
+			"",
+			"",
+		},
+		{
+			"quorum reached",
+			options.QuorumCheck{Ports: ports, Min: 3},
+			"",
+			"1 checks failed without breaking the quorum: " + closedPort,
+		},
+		{
+			"quorum reached by percentage",
+			options.QuorumCheck{Ports: ports, Scripts: []options.Script{{Name: "true"}}, MinPercent: 80},
+			"",
+			"1 checks failed without breaking the quorum: " + closedPort,
+		},
+		{
+			"quorum not reached",
+			options.QuorumCheck{Ports: ports, Scripts: []options.Script{{Name: "false"}}, Min: 5},
+			"only 3 of 5 checks passed, expected at least 5. Failed: " + closedPort,
+			"",
+		},
+	}
+
+	for _, testCase := range testCases {
+		result := (&quorumCheck{quorum: testCase.quorum, opts: opts}).Run()
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+			assert.Contains(t, result.Err.Error(), "Script false", testCase.name)
+		}
+		if testCase.expectedWarning == "" {
+			assert.Nil(t, result.Warning, testCase.name)
+		} else if assert.NotNil(t, result.Warning, testCase.name) {
+			assert.Contains(t, result.Warning.Error(), testCase.expectedWarning, testCase.name)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// Check that Redis answers PING, and optionally that INFO reports the expected role and fields. A Redis that is loading
+// its dataset, or a replica that has lost its master, still accepts connections, so a TCP check can't catch these.
+type redisCheck struct {
+	redis options.RedisCheck
+	opts  *options.Options
+}
+
+func (c *redisCheck) Name() string {
+	return fmt.Sprintf("Redis check of %s", c.redis.Address)
+}
+
+func (c *redisCheck) Run() *checkResult {
+	return attemptRedisCheck(c.redis, c.opts)
+}
+
+func attemptRedisCheck(redis options.RedisCheck, opts *options.Options) *checkResult {
+	logger := opts.Logger
+	logger.Infof("Attempting to send PING to Redis at %s...", redis.Address)
+
+	conn, err := net.DialTimeout("tcp", redis.Address, defaultCheckTimeout)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(defaultCheckTimeout))
+	client := &redisClient{conn: conn, reader: bufio.NewReader(conn)}
+
+	if redis.Password != "" {
+		args := []string{"AUTH", redis.Password}
+		if redis.Username != "" {
+			args = []string{"AUTH", redis.Username, redis.Password}
+		}
+		if _, err := client.do(args...); err != nil {
+			return &checkResult{Err: err}
+		}
+	}
+
+	reply, err := client.do("PING")
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+	if reply != "PONG" {
+		return &checkResult{Err: UnexpectedRedisReply{command: "PING", expected: "PONG", actual: reply}}
+	}
+
+	if len(redis.Roles) == 0 && len(redis.ExpectInfo) == 0 {
+		return &checkResult{}
+	}
+
+	reply, err = client.do("INFO")
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+	info := parseRedisInfo(reply)
+
+	result := &checkResult{Details: map[string]string{}}
+	for _, field := range []string{"redis_version", "role", "loading", "master_link_status"} {
+		if value, ok := info[field]; ok {
+			result.Details[field] = value
+		}
+	}
+
+	if len(redis.Roles) > 0 && !containsString(redis.Roles, info["role"]) {
+		result.Err = UnexpectedRedisInfo{field: "role", expected: strings.Join(redis.Roles, " or "), actual: info["role"]}
+		return result
+	}
+
+	fields := []string{}
+	for field := range redis.ExpectInfo {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if actual, ok := info[field]; !ok || actual != redis.ExpectInfo[field] {
+			result.Err = UnexpectedRedisInfo{field: field, expected: redis.ExpectInfo[field], actual: actual}
+			return result
+		}
+	}
+
+	return result
+}
+
+// A minimal client for the Redis serialization protocol (RESP), which only supports the replies to simple commands
+type redisClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Send a command and return its reply. An error reply from Redis is returned as a RedisError.
+func (client *redisClient) do(args ...string) (string, error) {
+	command := strings.Builder{}
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := client.conn.Write([]byte(command.String())); err != nil {
+		return "", err
+	}
+	return client.readReply(args[0])
+}
+
+func (client *redisClient) readReply(command string) (string, error) {
+	line, err := client.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", UnexpectedRedisReply{command: command, expected: "a reply", actual: line}
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", RedisError{command: command, message: line[1:]}
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length > maxResponseSize {
+			return "", UnexpectedRedisReply{command: command, expected: "a bulk string", actual: line}
+		}
+		if length < 0 {
+			return "", nil
+		}
+		buf := make([]byte, length+2)
+		if _, err := io.ReadFull(client.reader, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:length]), nil
+	}
+	return "", UnexpectedRedisReply{command: command, expected: "a simple reply", actual: line}
+}
+
+// Parse the field:value lines returned by INFO, skipping section headers such as # Replication
+func parseRedisInfo(reply string) map[string]string {
+	info := map[string]string{}
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keyAndValue := strings.SplitN(line, ":", 2)
+		if len(keyAndValue) == 2 {
+			info[keyAndValue[0]] = keyAndValue[1]
+		}
+	}
+	return info
+}
+
+// Custom error types
+
+type RedisError struct {
+	command string
+	message string
+}
+
+func (err RedisError) Error() string {
+	return fmt.Sprintf("Redis replied to %s with an error: %s", err.command, err.message)
+}
+
+type UnexpectedRedisReply struct {
+	command  string
+	expected string
+	actual   string
+}
+
+func (err UnexpectedRedisReply) Error() string {
+	return fmt.Sprintf("expected %s in reply to %s but got %q", err.expected, err.command, err.actual)
+}
+
+type UnexpectedRedisInfo struct {
+	field    string
+	expected string
+	actual   string
+}
+
+func (err UnexpectedRedisInfo) Error() string {
+	if err.actual == "" {
+		return fmt.Sprintf("INFO did not report %s, expected %s", err.field, err.expected)
+	}
+	return fmt.Sprintf("INFO reported %s:%s, expected %s", err.field, err.actual, err.expected)
+}
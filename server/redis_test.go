@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptRedisCheck(t *testing.T) {
+	// Will *not* run parallel because we're opening random tcp ports
+	// and want to avoid port clashes
+	ports, err := test.GetFreePorts(2)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+	replicaAddress := test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0])
+	loadingAddress := test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[1])
+
+	// A replica that requires a password and has lost its master
+	replica, err := net.Listen("tcp", replicaAddress)
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer replica.Close()
+	go serveResp(replica, func(args []string) string {
+		switch {
+		case args[0] == "AUTH" && args[len(args)-1] == "s3cr3t":
+			return "+OK\r\n"
+		case args[0] == "AUTH":
+			return "-WRONGPASS invalid username-password pair\r\n"
+		case args[0] == "PING":
+			return "+PONG\r\n"
+		case args[0] == "INFO":
+			info := "# Server\r\nredis_version:6.2.6\r\n\r\n# Persistence\r\nloading:0\r\n\r\n# Replication\r\nrole:slave\r\nmaster_link_status:down\r\n"
+			return fmt.Sprintf("$%d\r\n%s\r\n", len(info), info)
+		}
+		return "-ERR unknown command\r\n"
+	})
+
+	// A master that is still loading its dataset, which Redis reports by answering PING with an error
+	loading, err := net.Listen("tcp", loadingAddress)
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer loading.Close()
+	go serveResp(loading, func(args []string) string {
+		return "-LOADING Redis is loading the dataset in memory\r\n"
+	})
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	testCases := []struct {
+		name        string
+		redis       options.RedisCheck
+		expectedErr string
+	}{
+		{"ping", options.RedisCheck{Address: replicaAddress, Password: "s3cr3t"}, ""},
+		{"username", options.RedisCheck{Address: replicaAddress, Username: "health", Password: "s3cr3t"}, ""},
+		{"wrong password", options.RedisCheck{Address: replicaAddress, Password: "wrong"}, "Redis replied to AUTH with an error: WRONGPASS"},
+		{"role", options.RedisCheck{Address: replicaAddress, Password: "s3cr3t", Roles: []string{"slave"}, ExpectInfo: map[string]string{"loading": "0"}}, ""},
+		{"wrong role", options.RedisCheck{Address: replicaAddress, Password: "s3cr3t", Roles: []string{"master"}}, "INFO reported role:slave, expected master"},
+		{"master link down", options.RedisCheck{Address: replicaAddress, Password: "s3cr3t", ExpectInfo: map[string]string{"master_link_status": "up"}}, "INFO reported master_link_status:down, expected up"},
+		{"missing field", options.RedisCheck{Address: replicaAddress, Password: "s3cr3t", ExpectInfo: map[string]string{"cluster_enabled": "1"}}, "INFO did not report cluster_enabled, expected 1"},
+		{"loading", options.RedisCheck{Address: loadingAddress}, "Redis replied to PING with an error: LOADING"},
+	}
+
+	for _, testCase := range testCases {
+		result := attemptRedisCheck(testCase.redis, opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+// Accept connections on l, and answer each RESP command received with the reply returned by handle
+func serveResp(l net.Listener, handle func(args []string) string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for {
+				args, err := readRespCommand(reader)
+				if err != nil {
+					return
+				}
+				conn.Write([]byte(handle(args)))
+			}
+		}(conn)
+	}
+}
+
+func readRespCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{}
+	for i := 0; i < count; i++ {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, strings.TrimRight(arg, "\r\n"))
+	}
+	return args, nil
+}
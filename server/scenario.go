@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// Check that a series of HTTP requests, such as logging in and then fetching data, all succeed within a timeout
+type scenarioCheck struct {
+	scenario options.ScenarioCheck
+	opts     *options.Options
+}
+
+func (c *scenarioCheck) Name() string {
+	return fmt.Sprintf("Scenario %s", c.scenario.Name)
+}
+
+func (c *scenarioCheck) Run() *checkResult {
+	return runScenario(c.scenario, c.opts)
+}
+
+// Run the steps of a scenario in order, stopping at the first step that fails. The latency of each step that ran is
+// reported in the details.
+func runScenario(scenario options.ScenarioCheck, opts *options.Options) *checkResult {
+	logger := opts.Logger
+	logger.Infof("Attempting to run the %d steps of scenario %s...", len(scenario.Steps), scenario.Name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), scenario.Timeout)
+	defer cancel()
+
+	client := &http.Client{}
+	variables := map[string]string{}
+	result := &checkResult{Details: map[string]string{}}
+
+	for i, step := range scenario.Steps {
+		start := time.Now()
+		err, warning := runScenarioStep(ctx, client, step, variables)
+		latency := time.Since(start)
+		result.Details[fmt.Sprintf("step_%d_latency", i+1)] = latency.String()
+
+		if err != nil {
+			result.Err = ScenarioStepFailed{index: i, step: step, latency: latency, err: err}
+			return result
+		}
+		if warning != nil && result.Warning == nil {
+			result.Warning = ScenarioStepFailed{index: i, step: step, latency: latency, err: warning}
+		}
+	}
+
+	return result
+}
+
+// Send the request for a single step, evaluate the response, and extract any variables from it
+func runScenarioStep(ctx context.Context, client *http.Client, step options.ScenarioStep, variables map[string]string) (err error, warning error) {
+	url := options.ExpandScenarioVariables(step.Url, variables)
+	request, err := http.NewRequestWithContext(ctx, step.Method, url, strings.NewReader(options.ExpandScenarioVariables(step.Body, variables)))
+	if err != nil {
+		return err, nil
+	}
+	for name, value := range step.Headers {
+		request.Header.Set(name, options.ExpandScenarioVariables(value, variables))
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err, nil
+	}
+
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, maxHttpBodySize))
+	if err != nil {
+		return err, nil
+	}
+
+	if !step.MatchesStatus(response.StatusCode) {
+		return UnexpectedHttpStatus(response.StatusCode), nil
+	}
+
+	var document interface{}
+	if needsJsonBody(step) {
+		if err := json.Unmarshal(body, &document); err != nil {
+			return InvalidJsonBody{err: err}, nil
+		}
+		if err, warning = evaluateJsonAssertions(document, step.JsonAssertions); err != nil {
+			return err, nil
+		}
+	}
+
+	for _, extraction := range step.Extractions {
+		value, err := extractScenarioVariable(extraction, response, body, document)
+		if err != nil {
+			return err, nil
+		}
+		variables[extraction.Variable] = value
+	}
+
+	return nil, warning
+}
+
+func needsJsonBody(step options.ScenarioStep) bool {
+	if len(step.JsonAssertions) > 0 {
+		return true
+	}
+	for _, extraction := range step.Extractions {
+		if extraction.JsonPath != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract a value from a header, a JSON field or the body of a response, optionally narrowed down with a regex
+func extractScenarioVariable(extraction options.ScenarioExtraction, response *http.Response, body []byte, document interface{}) (string, error) {
+	var value string
+	switch {
+	case extraction.Header != "":
+		if _, ok := response.Header[http.CanonicalHeaderKey(extraction.Header)]; !ok {
+			return "", ExtractionFailed{variable: extraction.Variable, reason: fmt.Sprintf("there is no %s header", extraction.Header)}
+		}
+		value = response.Header.Get(extraction.Header)
+	case extraction.JsonPath != "":
+		field, ok := lookupJsonPath(document, extraction.JsonPath)
+		if !ok {
+			return "", ExtractionFailed{variable: extraction.Variable, reason: fmt.Sprintf("%s is missing", extraction.JsonPath)}
+		}
+		value = formatJsonValue(field)
+	default:
+		value = string(body)
+	}
+
+	if extraction.Regex == nil {
+		return value, nil
+	}
+
+	match := extraction.Regex.FindStringSubmatch(value)
+	if match == nil {
+		return "", ExtractionFailed{variable: extraction.Variable, reason: fmt.Sprintf("nothing matched %q", extraction.Regex)}
+	}
+	if len(match) > 1 {
+		return match[1], nil
+	}
+	return match[0], nil
+}
+
+// Custom error types
+
+type ScenarioStepFailed struct {
+	index   int
+	step    options.ScenarioStep
+	latency time.Duration
+	err     error
+}
+
+func (err ScenarioStepFailed) Error() string {
+	if err.step.Name == "" {
+		return fmt.Sprintf("step %d (%s %s) failed after %s: %s", err.index+1, err.step.Method, err.step.Url, err.latency, err.err)
+	}
+	return fmt.Sprintf("step %d (%s) failed after %s: %s", err.index+1, err.step.Name, err.latency, err.err)
+}
+
+type ExtractionFailed struct {
+	variable string
+	reason   string
+}
+
+func (err ExtractionFailed) Error() string {
+	return fmt.Sprintf("could not extract %s: %s", err.variable, err.reason)
+}
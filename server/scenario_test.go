@@ -0,0 +1,121 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunScenario(t *testing.T) {
+	t.Parallel()
+
+	// A fake app where a client logs in to get a token, and then uses the token to list items
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/login" && r.Method == http.MethodPost:
+			body, _ := ioutil.ReadAll(r.Body)
+			if string(body) != `{"user": "health"}` {
+				http.Error(w, "bad credentials", http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Set-Cookie", "session=s3cr3t; Path=/")
+			fmt.Fprint(w, `{"token": "abc123", "user": {"id": 42}}`)
+		case r.URL.Path == "/users/42/items":
+			if r.Header.Get("Authorization") != "Bearer abc123" || r.Header.Get("Cookie") != "session=s3cr3t" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"items": [{"name": "first"}], "cache": "stale"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "health-checker-scenario-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	login := fmt.Sprintf(`{"name": "login", "method": "POST", "url": "%s/login", "body": "{\"user\": \"health\"}", "expect-json": ["token exists"], "extract": [
+		{"var": "token", "json": "token"},
+		{"var": "user_id", "json": "user.id"},
+		{"var": "session", "header": "Set-Cookie", "regex": "session=([^;]+)"}
+	]}`, server.URL)
+	items := `{"name": "items", "url": "%s/users/${user_id}/items", "headers": {"Authorization": "Bearer ${token}", "Cookie": "session=${session}"}, %s}`
+
+	testCases := []struct {
+		name            string
+		steps           []string
+		expectedErr     string
+		expectedWarning string
+	}{
+		{
+			"log in and list items",
+			[]string{login, fmt.Sprintf(items, server.URL, `"expect-json": ["items.0.name == first"]`)},
+			"",
+			"",
+		},
+		{
+			"degraded step",
+			[]string{login, fmt.Sprintf(items, server.URL, `"warn-json": ["cache == fresh"]`)},
+			"",
+			`step 2 (items) failed after`,
+		},
+		{
+			"failed assertion",
+			[]string{login, fmt.Sprintf(items, server.URL, `"expect-json": ["items.1.name exists"]`)},
+			"expected items.1.name exists but items.1.name is missing",
+			"",
+		},
+		{
+			"unexpected status",
+			[]string{fmt.Sprintf(`{"url": "%s/missing"}`, server.URL)},
+			fmt.Sprintf("step 1 (GET %s/missing) failed after", server.URL),
+			"",
+		},
+		{
+			"failed extraction",
+			[]string{fmt.Sprintf(`{"url": "%s/login", "method": "POST", "body": "{\"user\": \"health\"}", "extract": [{"var": "id", "header": "X-Request-Id"}]}`, server.URL)},
+			"could not extract id: there is no X-Request-Id header",
+			"",
+		},
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	for i, testCase := range testCases {
+		path := filepath.Join(dir, fmt.Sprintf("scenario-%d.json", i))
+		contents := fmt.Sprintf(`{"steps": [%s]}`, strings.Join(testCase.steps, ", "))
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			assert.FailNow(t, "Failed to write scenario: %v", err.Error())
+		}
+
+		scenarios, err := options.ParseScenarioChecks([]string{"file=" + path})
+		if !assert.Nil(t, err, testCase.name) {
+			continue
+		}
+
+		result := runScenario(scenarios[0], opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+		}
+		if testCase.expectedWarning == "" {
+			assert.Nil(t, result.Warning, testCase.name)
+		} else if assert.NotNil(t, result.Warning, testCase.name) {
+			assert.Contains(t, result.Warning.Error(), testCase.expectedWarning, testCase.name)
+		}
+		assert.Contains(t, result.Details, "step_1_latency", testCase.name)
+	}
+}
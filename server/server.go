@@ -1,12 +1,9 @@
 package server
 
 import (
-	"context"
 	"fmt"
 	"net"
 	"net/http"
-	"os/exec"
-	"sync"
 	"time"
 
 	"github.com/gruntwork-io/go-commons/errors"
@@ -20,105 +17,90 @@ type httpResponse struct {
 }
 
 func StartHttpServer(opts *options.Options) error {
-	http.HandleFunc("/", httpHandler(opts))
+	runner := newCheckRunner(opts)
+	errs := make(chan error, 2)
 
-	err := http.ListenAndServe(opts.Listener, nil)
-	if err != nil {
-		return err
-	}
+	startLogTailers(opts)
 
-	return nil
-}
+	if opts.HaproxyAgentListener != "" {
+		go func() {
+			errs <- runner.serveHaproxyAgent(opts.HaproxyAgentListener)
+		}()
+	}
 
-func httpHandler(opts *options.Options) http.HandlerFunc {
-	var group singleflight.Group
+	go func() {
+		http.HandleFunc("/", runner.httpHandler())
+		errs <- http.ListenAndServe(opts.Listener, nil)
+	}()
 
-	return func(w http.ResponseWriter, r *http.Request) {
-		var resp *httpResponse
-		logger := opts.Logger
-
-		// In Singleflight mode only one runChecks pass will be performed
-		// at any given time, with the result being shared across concurrent
-		// inbound requests
-		if opts.Singleflight {
-			logger.Infof("Received inbound request. Performing singleflight health checks...")
-
-			result, _, shared := group.Do("check", func() (interface{}, error) {
-				logger.Infof("Beginning health checks...")
-				return runChecks(opts), nil
-			})
-
-			if shared {
-				logger.Infof("Singleflight health check response was shared between multiple requests.")
-			}
-
-			resp = result.(*httpResponse)
-		} else {
-			logger.Infof("Received inbound request. Beginning health checks...")
-			resp = runChecks(opts)
-		}
+	// Both servers run until they fail, so return as soon as either one of them does
+	return <-errs
+}
 
-		err := writeHttpResponse(w, resp)
-		if err != nil {
-			opts.Logger.Error("Failed to send HTTP response. Exiting.")
-			panic(err)
-		}
-	}
+// Runs the configured checks on behalf of the HTTP server and any other listeners, making sure that concurrent
+// requests share a single pass of the checks when singleflight mode is enabled
+type checkRunner struct {
+	opts  *options.Options
+	group singleflight.Group
 }
 
-// Check that we can open a TPC connection to all the ports in opts.Ports
-func runChecks(opts *options.Options) *httpResponse {
-	logger := opts.Logger
-	allChecksOk := true
-
-	var waitGroup = sync.WaitGroup{}
-
-	for _, port := range opts.Ports {
-		waitGroup.Add(1)
-		go func(port int) {
-			err := attemptTcpConnection(port, opts)
-			if err != nil {
-				logger.Warnf("TCP connection to port %d FAILED: %s", port, err)
-				allChecksOk = false
-			} else {
-				logger.Infof("TCP connection to port %d successful", port)
-			}
-
-			waitGroup.Done()
-		}(port)
-	}
+func newCheckRunner(opts *options.Options) *checkRunner {
+	return &checkRunner{opts: opts}
+}
 
-	for _, script := range opts.Scripts {
-		waitGroup.Add(1)
-		go func(script options.Script) {
+func (runner *checkRunner) run() []*checkResult {
+	logger := runner.opts.Logger
 
-			defer waitGroup.Done()
+	// In Singleflight mode only one performChecks pass will be performed
+	// at any given time, with the result being shared across concurrent
+	// inbound requests
+	if runner.opts.Singleflight {
+		logger.Infof("Received inbound request. Performing singleflight health checks...")
 
-			logger.Infof("Executing '%v' with a timeout of %v seconds...", script, opts.ScriptTimeout)
+		results, _, shared := runner.group.Do("check", func() (interface{}, error) {
+			logger.Infof("Beginning health checks...")
+			return performChecks(runner.opts), nil
+		})
 
-			timeout := time.Second * time.Duration(opts.ScriptTimeout)
+		if shared {
+			logger.Infof("Singleflight health check response was shared between multiple requests.")
+		}
 
-			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		return results.([]*checkResult)
+	}
 
-			defer cancel()
+	logger.Infof("Received inbound request. Beginning health checks...")
+	return performChecks(runner.opts)
+}
 
-			cmd := exec.CommandContext(ctx, script.Name, script.Args...)
+func httpHandler(opts *options.Options) http.HandlerFunc {
+	return newCheckRunner(opts).httpHandler()
+}
 
-			output, err := cmd.Output()
+func (runner *checkRunner) httpHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := newHttpResponse(runner.opts, runner.run())
 
-			if err != nil {
-				logger.Warnf("Script %v FAILED: %s", script.Name, err)
-				logger.Warnf("Command output: %s", output)
-				allChecksOk = false
-			} else {
-				logger.Infof("Script %v successful", script)
-			}
-		}(script)
+		err := writeHttpResponse(w, resp)
+		if err != nil {
+			runner.opts.Logger.Error("Failed to send HTTP response. Exiting.")
+			panic(err)
+		}
 	}
+}
 
-	waitGroup.Wait()
+// Run all the checks in opts and return the HTTP response that summarizes them
+func runChecks(opts *options.Options) *httpResponse {
+	return newHttpResponse(opts, performChecks(opts))
+}
+
+func newHttpResponse(opts *options.Options, results []*checkResult) *httpResponse {
+	logger := opts.Logger
 
-	if allChecksOk {
+	if countPassed(results) == len(results) && countDegraded(results) > 0 {
+		logger.Infof("All health checks passed, but at least one is degraded. Returning HTTP 200 response.\n")
+		return &httpResponse{StatusCode: http.StatusOK, Body: "OK, but at least one health check is degraded"}
+	} else if countPassed(results) == len(results) {
 		logger.Infof("All health checks passed. Returning HTTP 200 response.\n")
 		return &httpResponse{StatusCode: http.StatusOK, Body: "OK"}
 	} else {
@@ -127,23 +109,67 @@ func runChecks(opts *options.Options) *httpResponse {
 	}
 }
 
-// Attempt to open a TCP connection to the given port
-func attemptTcpConnection(port int, opts *options.Options) error {
+// Attempt to open a TCP connection to the port in the given check. If the check has a payload, send it, and if it
+// expects a response, wait for one that matches. The dial, send and receive all share one timeout.
+func attemptTcpConnection(tcp options.TcpCheck, opts *options.Options) error {
 	logger := opts.Logger
-	logger.Infof("Attempting to connect to port %d via TCP...", port)
-
-	defaultTimeout := time.Second * 5
+	logger.Infof("Attempting to connect to port %d via TCP...", tcp.Port)
 
-	conn, err := net.DialTimeout("tcp", fmt.Sprintf("0.0.0.0:%d", port), defaultTimeout)
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("0.0.0.0:%d", tcp.Port), defaultCheckTimeout)
 	if err != nil {
 		return err
 	}
 
 	defer conn.Close()
 
+	if len(tcp.Payload) == 0 && !tcp.ExpectsResponse() {
+		return nil
+	}
+
+	conn.SetDeadline(time.Now().Add(defaultCheckTimeout))
+
+	if len(tcp.Payload) > 0 {
+		if _, err := conn.Write(tcp.Payload); err != nil {
+			return err
+		}
+	}
+
+	if !tcp.ExpectsResponse() {
+		return nil
+	}
+
+	response, err := readUntil(conn, tcp.MatchesResponse)
+	logger.Debugf("Received response from port %d: %q", tcp.Port, response)
+	if err != nil {
+		return UnexpectedResponse{expected: tcp.DescribeExpectedResponse(), actual: string(response), err: err}
+	}
+
 	return nil
 }
 
+// The most we will read from a connection while waiting for an expected response
+const maxResponseSize = 64 * 1024
+
+// Read from conn until done returns true for everything read so far, the connection is closed, or the connection's
+// deadline passes. Returns everything that was read.
+func readUntil(conn net.Conn, done func(response []byte) bool) ([]byte, error) {
+	response := []byte{}
+	buf := make([]byte, 4096)
+
+	for len(response) < maxResponseSize {
+		n, err := conn.Read(buf)
+		response = append(response, buf[:n]...)
+		if done(response) {
+			return response, nil
+		}
+		if err != nil {
+			return response, err
+		}
+	}
+
+	return response, fmt.Errorf("gave up after reading %d bytes", len(response))
+}
+
 func writeHttpResponse(w http.ResponseWriter, resp *httpResponse) error {
 	w.WriteHeader(resp.StatusCode)
 	_, err := w.Write([]byte(resp.Body))
@@ -153,3 +179,18 @@ func writeHttpResponse(w http.ResponseWriter, resp *httpResponse) error {
 
 	return nil
 }
+
+// Custom error types
+
+type UnexpectedResponse struct {
+	expected string
+	actual   string
+	err      error
+}
+
+func (err UnexpectedResponse) Error() string {
+	if err.err != nil {
+		return fmt.Sprintf("expected a response %s but got %q before %s", err.expected, err.actual, err.err)
+	}
+	return fmt.Sprintf("expected a response %s but got %q", err.expected, err.actual)
+}
@@ -1,11 +1,15 @@
 package server
 
 import (
+	"bufio"
+	"errors"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"regexp"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -220,6 +224,88 @@ func TestSingleflight(t *testing.T) {
 
 }
 
+func TestNewHttpResponse(t *testing.T) {
+	t.Parallel()
+
+	passed := &checkResult{}
+	degraded := &checkResult{Warning: errors.New("degraded")}
+	failed := &checkResult{Err: errors.New("failed")}
+
+	testCases := []struct {
+		name           string
+		results        []*checkResult
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"all passed", []*checkResult{passed, passed}, 200, "OK"},
+		{"one degraded", []*checkResult{passed, degraded}, 200, "OK, but at least one health check is degraded"},
+		{"one failed", []*checkResult{degraded, failed}, 504, "At least one health check failed"},
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	for _, testCase := range testCases {
+		response := newHttpResponse(opts, testCase.results)
+		assert.Equal(t, testCase.expectedStatus, response.StatusCode, testCase.name)
+		assert.Equal(t, testCase.expectedBody, response.Body, testCase.name)
+	}
+}
+
+func TestAttemptTcpConnectionSendExpect(t *testing.T) {
+	// Will *not* run parallel because we're opening random tcp ports
+	// and want to avoid port clashes
+	ports, err := test.GetFreePorts(2)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+	redisPort, sshPort := ports[0], ports[1]
+
+	// A fake Redis that answers every PING with +PONG, and hangs up after any other command like it does after a
+	// protocol error
+	redis, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, redisPort))
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer redis.Close()
+	go serveLines(redis, "", func(line string) (string, bool) {
+		if line == "PING" {
+			return "+PONG\r\n", false
+		}
+		return "-ERR unknown command\r\n", true
+	})
+
+	// A fake SSH server that sends a banner as soon as a client connects
+	ssh, err := net.Listen("tcp", test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, sshPort))
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer ssh.Close()
+	go serveLines(ssh, "SSH-2.0-OpenSSH_8.2\r\n", nil)
+
+	testCases := []struct {
+		name        string
+		tcp         options.TcpCheck
+		expectedErr string
+	}{
+		{"send and expect substring", options.TcpCheck{Port: redisPort, Payload: []byte("PING\r\n"), Expect: "+PONG"}, ""},
+		{"send and expect regex", options.TcpCheck{Port: redisPort, Payload: []byte("PING\r\n"), ExpectRegex: regexp.MustCompile(`^\+PONG\r\n$`)}, ""},
+		{"send and get wrong reply", options.TcpCheck{Port: redisPort, Payload: []byte("PONG\r\n"), Expect: "+PONG"}, `expected a response containing "+PONG" but got "-ERR unknown command\r\n"`},
+		{"banner", options.TcpCheck{Port: sshPort, ExpectRegex: regexp.MustCompile(`^SSH-2\.0-`)}, ""},
+		{"wrong banner", options.TcpCheck{Port: sshPort, Expect: "220 "}, "expected a response containing \"220 \""},
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	for _, testCase := range testCases {
+		err := attemptTcpConnection(testCase.tcp, opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err, testCase.name)
+		} else if assert.NotNil(t, err, testCase.name) {
+			assert.Contains(t, err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
 func closeListeners(t *testing.T, listeners []net.Listener) {
 	for _, l := range listeners {
 		err := l.Close()
@@ -231,15 +317,12 @@ func closeListeners(t *testing.T, listeners []net.Listener) {
 
 func handleRequests(t *testing.T, l net.Listener, counter *int32) {
 	for {
-		// Listen for an incoming connection.
-		l.Accept()
-		// We don't log these when testing because we're forcibly closing the socket
-		// from the outside. If you're debugging and wish to enable the logging,
-		// uncomment the lines below
-		//_, err := l.Accept()
-		//if err != nil {
-		//	t.Logf("Error accepting: %s", err.Error())
-		//}
+		// Listen for an incoming connection. We don't log errors when testing because
+		// we're forcibly closing the socket from the outside, but we do stop, since
+		// every later Accept would fail immediately and spin, starving other tests.
+		if _, err := l.Accept(); err != nil {
+			return
+		}
 
 		if counter != nil {
 			atomic.AddInt32(counter, 1)
@@ -247,6 +330,34 @@ func handleRequests(t *testing.T, l net.Listener, counter *int32) {
 	}
 }
 
+// Accept connections on l, send the given banner and then answer each line received with the reply returned by
+// handle, until either handle or the client hangs up. If handle is nil, the connection is closed right after the
+// banner. Hanging up lets a client that is waiting for a different reply fail at once instead of at its deadline.
+func serveLines(l net.Listener, banner string, handle func(line string) (reply string, hangUp bool)) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+			conn.Write([]byte(banner))
+			if handle == nil {
+				return
+			}
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				reply, hangUp := handle(strings.TrimRight(scanner.Text(), "\r"))
+				conn.Write([]byte(reply))
+				if hangUp {
+					return
+				}
+			}
+		}(conn)
+	}
+}
+
 func createOptionsForTest(t *testing.T, scriptTimeout int, scripts []string, listener string, ports []int) *options.Options {
 	logger := logging.GetLogger("health-checker")
 	logger.Out = os.Stdout
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// The value of the st column in /proc/net/tcp for a socket in the LISTEN state
+const tcpListenState = "0A"
+
+// Check that the TCP socket listening on a port is owned by the expected process, rather than by whatever else
+// happens to have grabbed the port
+type socketOwnerCheck struct {
+	socketOwner options.SocketOwnerCheck
+	opts        *options.Options
+}
+
+func (c *socketOwnerCheck) Name() string {
+	return fmt.Sprintf("Owner of the socket listening on port %d", c.socketOwner.Port)
+}
+
+func (c *socketOwnerCheck) Run() *checkResult {
+	return &checkResult{Err: attemptSocketOwnerCheck(c.socketOwner, c.opts)}
+}
+
+func attemptSocketOwnerCheck(socketOwner options.SocketOwnerCheck, opts *options.Options) error {
+	logger := opts.Logger
+	logger.Infof("Attempting to find the owner of the socket listening on port %d in %s...", socketOwner.Port, opts.ProcRoot)
+
+	inodes, err := findListeningSocketInodes(opts.ProcRoot, socketOwner.Port)
+	if err != nil {
+		return err
+	}
+	if len(inodes) == 0 {
+		return NoListeningSocket(socketOwner.Port)
+	}
+
+	processes, err := findProcesses(opts.ProcRoot, socketOwner.Matcher)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range processes {
+		if processOwnsSocket(opts.ProcRoot, p.Pid, inodes) {
+			logger.Debugf("Process %d (%s) owns the socket listening on port %d", p.Pid, p.Name, socketOwner.Port)
+			return nil
+		}
+	}
+
+	// To make the failure easier to debug, look for whichever process does own the socket
+	owners, err := findProcesses(opts.ProcRoot, options.ProcessMatcher{})
+	if err != nil {
+		return err
+	}
+	for _, p := range owners {
+		if processOwnsSocket(opts.ProcRoot, p.Pid, inodes) {
+			return WrongSocketOwner{port: socketOwner.Port, matcher: socketOwner.Matcher, owner: fmt.Sprintf("process %d (%s)", p.Pid, p.Name)}
+		}
+	}
+
+	return WrongSocketOwner{port: socketOwner.Port, matcher: socketOwner.Matcher, owner: "a process that could not be inspected"}
+}
+
+// Find the inodes of the TCP sockets listening on the given port in /proc/net/tcp and /proc/net/tcp6
+func findListeningSocketInodes(procRoot string, port int) (map[string]bool, error) {
+	inodes := map[string]bool{}
+	found := false
+
+	for _, name := range []string{"tcp", "tcp6"} {
+		file, err := os.Open(filepath.Join(procRoot, "net", name))
+		if os.IsNotExist(err) {
+			// tcp6 does not exist if IPv6 is disabled
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			// Lines look like "0: 00000000:1F90 00000000:0000 0A ... 1000 0 5555 ...", where the local address is
+			// ip:port in hex, and the inode is the tenth field
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 || fields[3] != tcpListenState {
+				continue
+			}
+			address := strings.Split(fields[1], ":")
+			localPort, err := strconv.ParseInt(address[len(address)-1], 16, 32)
+			if err != nil || int(localPort) != port {
+				continue
+			}
+			inodes[fields[9]] = true
+		}
+		file.Close()
+	}
+
+	if !found {
+		return nil, fmt.Errorf("could not find %s", filepath.Join(procRoot, "net", "tcp"))
+	}
+	return inodes, nil
+}
+
+// Return true if any of the file descriptors of the given process is a socket with one of the given inodes
+func processOwnsSocket(procRoot string, pid int, inodes map[string]bool) bool {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid), "fd")
+	fds, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+
+	for _, fd := range fds {
+		// Sockets are symlinks to "socket:[<inode>]"
+		target, err := os.Readlink(filepath.Join(dir, fd.Name()))
+		if err != nil || !strings.HasPrefix(target, "socket:[") {
+			continue
+		}
+		if inodes[strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")] {
+			return true
+		}
+	}
+	return false
+}
+
+// Custom error types
+
+type NoListeningSocket int
+
+func (port NoListeningSocket) Error() string {
+	return fmt.Sprintf("nothing is listening on port %d", int(port))
+}
+
+type WrongSocketOwner struct {
+	port    int
+	matcher options.ProcessMatcher
+	owner   string
+}
+
+func (err WrongSocketOwner) Error() string {
+	return fmt.Sprintf("port %d is owned by %s, not by a process %s", err.port, err.owner, err.matcher)
+}
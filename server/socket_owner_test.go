@@ -0,0 +1,65 @@
+package server
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptSocketOwnerCheck(t *testing.T) {
+	t.Parallel()
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+	opts.ProcRoot = testProcRoot
+
+	// In the fixtures, the kafka process (pid 100) listens on port 8080 via IPv4, a worker (pid 102) listens on port
+	// 9090 via IPv6, and port 3306 is listening but owned by a process we can't see. Pid 103 has an established
+	// connection on port 8080, which doesn't count.
+	testCases := []struct {
+		name        string
+		socketOwner options.SocketOwnerCheck
+		expectedErr string
+	}{
+		{
+			"owned by pidfile",
+			options.SocketOwnerCheck{Port: 8080, Matcher: options.ProcessMatcher{Pidfile: "testdata/kafka.pid"}},
+			"",
+		},
+		{
+			"owned by cmdline via IPv6",
+			options.SocketOwnerCheck{Port: 9090, Matcher: options.ProcessMatcher{Cmdline: regexp.MustCompile(`--queue default`)}},
+			"",
+		},
+		{
+			"owned by another process",
+			options.SocketOwnerCheck{Port: 9090, Matcher: options.ProcessMatcher{Name: "java"}},
+			"port 9090 is owned by process 102 (worker), not by a process named java",
+		},
+		{
+			"established connections are ignored",
+			options.SocketOwnerCheck{Port: 8080, Matcher: options.ProcessMatcher{Name: "my (weird) app"}},
+			"port 8080 is owned by process 100 (java), not by a process named my (weird) app",
+		},
+		{
+			"owner unknown",
+			options.SocketOwnerCheck{Port: 3306, Matcher: options.ProcessMatcher{Name: "mysqld"}},
+			"port 3306 is owned by a process that could not be inspected, not by a process named mysqld",
+		},
+		{
+			"nothing listening",
+			options.SocketOwnerCheck{Port: 5432, Matcher: options.ProcessMatcher{Name: "postgres"}},
+			"nothing is listening on port 5432",
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := attemptSocketOwnerCheck(testCase.socketOwner, opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err, testCase.name)
+		} else if assert.NotNil(t, err, testCase.name) {
+			assert.Equal(t, testCase.expectedErr, err.Error(), testCase.name)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// We only read this much of a file passed to read_file, the same limit we use for HTTP bodies
+const maxStarlarkFileSize = maxHttpBodySize
+
+// Check the outcome of a Starlark script, which runs in-process with a limited number of steps and a timeout, and can
+// only reach the outside world through the built-ins we give it
+type starlarkCheck struct {
+	starlark options.StarlarkCheck
+	opts     *options.Options
+}
+
+func (c *starlarkCheck) Name() string {
+	return fmt.Sprintf("Starlark %s", c.starlark.Name)
+}
+
+func (c *starlarkCheck) Run() *checkResult {
+	return runStarlark(c.starlark, c.opts)
+}
+
+// Run the script and then its check() function. The check passes unless the script fails, whether by calling fail(),
+// returning False from check(), hitting a runtime error, or running out of steps or time.
+func runStarlark(check options.StarlarkCheck, opts *options.Options) *checkResult {
+	logger := opts.Logger
+	logger.Infof("Attempting to run Starlark script %s...", check.Path)
+
+	ctx, cancel := context.WithTimeout(context.Background(), check.Timeout)
+	defer cancel()
+
+	result := &checkResult{Details: map[string]string{}}
+	thread := &starlark.Thread{
+		Name: check.Name,
+		Print: func(_ *starlark.Thread, msg string) {
+			logger.Infof("Starlark %s: %s", check.Name, msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(check.MaxSteps)
+
+	// The built-ins stop waiting on the network when the context expires, and this stops the interpreter itself
+	timer := time.AfterFunc(check.Timeout, func() {
+		thread.Cancel(fmt.Sprintf("the script did not finish within %s", check.Timeout))
+	})
+	defer timer.Stop()
+
+	// Freeze the globals like starlark.ExecFile does, so that check() can't keep state from one run to the next
+	globals, err := check.Program.Init(thread, starlarkBuiltins(ctx, result))
+	globals.Freeze()
+	if err == nil {
+		err = callStarlarkCheck(thread, check, globals)
+	}
+	result.Details["steps"] = fmt.Sprintf("%d", thread.ExecutionSteps())
+
+	if err != nil {
+		result.Err = newStarlarkScriptFailed(err)
+	}
+	return result
+}
+
+// Call the check() function defined by the script
+func callStarlarkCheck(thread *starlark.Thread, check options.StarlarkCheck, globals starlark.StringDict) error {
+	function, ok := globals["check"].(starlark.Callable)
+	if !ok {
+		return MissingStarlarkCheckFunction(check.Path)
+	}
+
+	value, err := starlark.Call(thread, function, nil, nil)
+	if err != nil {
+		return err
+	}
+	if value == starlark.False {
+		return StarlarkScriptFailed{message: "check() returned False"}
+	}
+	return nil
+}
+
+// The values predeclared for a script, one for each of options.StarlarkBuiltins. Failures of the network built-ins are
+// returned to the script rather than raised, since Starlark has no way to catch an error, and a script will often want
+// to handle them itself.
+func starlarkBuiltins(ctx context.Context, result *checkResult) starlark.StringDict {
+	return starlark.StringDict{
+		"json":   json.Module,
+		"struct": starlark.NewBuiltin("struct", starlarkstruct.Make),
+
+		// tcp_dial(address) returns struct(ok, error)
+		"tcp_dial": starlark.NewBuiltin("tcp_dial", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var address string
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "address", &address); err != nil {
+				return nil, err
+			}
+
+			dialer := net.Dialer{}
+			conn, err := dialer.DialContext(ctx, "tcp", address)
+			if err != nil {
+				return starlarkOutcome(err, nil), nil
+			}
+			conn.Close()
+			return starlarkOutcome(nil, nil), nil
+		}),
+
+		// http_get(url, headers={}) returns struct(ok, status, body, error). A response with any status is ok.
+		"http_get": starlark.NewBuiltin("http_get", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var url string
+			var headers *starlark.Dict
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "url", &url, "headers?", &headers); err != nil {
+				return nil, err
+			}
+
+			request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return starlarkOutcome(err, starlark.StringDict{"status": starlark.MakeInt(0), "body": starlark.String("")}), nil
+			}
+			if headers != nil {
+				for _, item := range headers.Items() {
+					name, nameOk := starlark.AsString(item[0])
+					value, valueOk := starlark.AsString(item[1])
+					if !nameOk || !valueOk {
+						return nil, fmt.Errorf("%s: headers must map strings to strings", b.Name())
+					}
+					request.Header.Set(name, value)
+				}
+			}
+
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				return starlarkOutcome(err, starlark.StringDict{"status": starlark.MakeInt(0), "body": starlark.String("")}), nil
+			}
+			defer response.Body.Close()
+
+			body, err := ioutil.ReadAll(io.LimitReader(response.Body, maxHttpBodySize))
+			return starlarkOutcome(err, starlark.StringDict{
+				"status": starlark.MakeInt(response.StatusCode),
+				"body":   starlark.String(body),
+			}), nil
+		}),
+
+		// read_file(path) returns the contents of the file, or None if it can't be read
+		"read_file": starlark.NewBuiltin("read_file", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var path string
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "path", &path); err != nil {
+				return nil, err
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				return starlark.None, nil
+			}
+			defer file.Close()
+
+			contents, err := ioutil.ReadAll(io.LimitReader(file, maxStarlarkFileSize))
+			if err != nil {
+				return starlark.None, nil
+			}
+			return starlark.String(contents), nil
+		}),
+
+		// warn(message) degrades the check without failing it. Only the first warning is reported.
+		"warn": starlark.NewBuiltin("warn", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var message string
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "message", &message); err != nil {
+				return nil, err
+			}
+			if result.Warning == nil {
+				result.Warning = StarlarkScriptWarning(message)
+			}
+			return starlark.None, nil
+		}),
+
+		// detail(name, value) reports a value in the details of the check
+		"detail": starlark.NewBuiltin("detail", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var name string
+			var value starlark.Value
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name, "value", &value); err != nil {
+				return nil, err
+			}
+			if s, ok := starlark.AsString(value); ok {
+				result.Details[name] = s
+			} else {
+				result.Details[name] = value.String()
+			}
+			return starlark.None, nil
+		}),
+	}
+}
+
+// Return the struct a network built-in gives back to the script, with ok and error fields describing whether it
+// succeeded, plus any other fields
+func starlarkOutcome(err error, fields starlark.StringDict) *starlarkstruct.Struct {
+	if fields == nil {
+		fields = starlark.StringDict{}
+	}
+	fields["ok"] = starlark.Bool(err == nil)
+	fields["error"] = starlark.String("")
+	if err != nil {
+		fields["error"] = starlark.String(err.Error())
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, fields)
+}
+
+// Convert an error from the interpreter into one that points at the line of the script that failed
+func newStarlarkScriptFailed(err error) error {
+	evalErr, ok := err.(*starlark.EvalError)
+	if !ok {
+		return err
+	}
+
+	failed := StarlarkScriptFailed{message: strings.TrimPrefix(evalErr.Msg, "fail: ")}
+	for i := range evalErr.CallStack {
+		if pos := evalErr.CallStack.At(i).Pos; pos.IsValid() && pos.Filename() != "<builtin>" {
+			failed.position = pos.String()
+			break
+		}
+	}
+	return failed
+}
+
+// Custom error types
+
+type StarlarkScriptFailed struct {
+	position string
+	message  string
+}
+
+func (err StarlarkScriptFailed) Error() string {
+	if err.position == "" {
+		return err.message
+	}
+	return fmt.Sprintf("%s: %s", err.position, err.message)
+}
+
+type StarlarkScriptWarning string
+
+func (warning StarlarkScriptWarning) Error() string {
+	return string(warning)
+}
+
+type MissingStarlarkCheckFunction string
+
+func (path MissingStarlarkCheckFunction) Error() string {
+	return fmt.Sprintf("%s does not define a check() function", string(path))
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunStarlark(t *testing.T) {
+	t.Parallel()
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"role": "replica", "lag_seconds": 12}`)
+	}))
+	defer server.Close()
+
+	// Nothing listens on the address of a listener that has been closed
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		assert.FailNow(t, "Failed to listen: %v", err.Error())
+	}
+	closed.Close()
+
+	dir, err := ioutil.TempDir("", "health-checker-starlark-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	maintenance := filepath.Join(dir, "maintenance")
+	if err := ioutil.WriteFile(maintenance, []byte("upgrading\n"), 0644); err != nil {
+		assert.FailNow(t, "Failed to write file: %v", err.Error())
+	}
+
+	testCases := []struct {
+		name            string
+		source          string
+		expectedErr     string
+		expectedWarning string
+		expectedDetails map[string]string
+	}{
+		{
+			"pass",
+			"def check():\n    pass\n",
+			"",
+			"",
+			nil,
+		},
+		{
+			"fail",
+			"def check():\n    fail(\"replica is lagging\")\n",
+			"check.star:2:9: replica is lagging",
+			"",
+			nil,
+		},
+		{
+			"returned false",
+			"def check():\n    return 1 > 2\n",
+			"check() returned False",
+			"",
+			nil,
+		},
+		{
+			"runtime error",
+			"def check():\n    return {}[\"missing\"]\n",
+			"check.star:2:14: key \"missing\" not in dict",
+			"",
+			nil,
+		},
+		{
+			"no check function",
+			"x = 1\n",
+			"check.star does not define a check() function",
+			"",
+			nil,
+		},
+		{
+			"load is not allowed",
+			"load(\"other.star\", \"x\")\ndef check():\n    pass\n",
+			"load not implemented",
+			"",
+			nil,
+		},
+		{
+			"too many steps",
+			"def check():\n    for i in range(100000000):\n        pass\n",
+			"Starlark computation cancelled: too many steps",
+			"",
+			nil,
+		},
+		{
+			"tcp dial",
+			fmt.Sprintf("def check():\n    if not tcp_dial(%q).ok:\n        fail(\"server is down\")\n    detail(\"closed\", tcp_dial(%q).ok)\n", server.Listener.Addr().String(), closed.Addr().String()),
+			"",
+			"",
+			map[string]string{"closed": "False"},
+		},
+		{
+			"http get and json",
+			fmt.Sprintf("def check():\n    response = http_get(%q, headers={\"Authorization\": \"Bearer secret\"})\n    status = json.decode(response.body)\n    detail(\"role\", status[\"role\"])\n    if status[\"lag_seconds\"] > 10:\n        warn(\"replica is %%d seconds behind\" %% status[\"lag_seconds\"])\n", server.URL),
+			"",
+			"replica is 12 seconds behind",
+			map[string]string{"role": "replica"},
+		},
+		{
+			"http get status",
+			fmt.Sprintf("def check():\n    response = http_get(%q)\n    if response.status != 200:\n        fail(\"unexpected status\", response.status)\n", server.URL),
+			"unexpected status 401",
+			"",
+			nil,
+		},
+		{
+			"http get with an invalid url",
+			"def check():\n    response = http_get(\"http://[::1\")\n    detail(\"status\", response.status)\n    if not response.ok:\n        fail(response.error)\n",
+			"missing ']' in host",
+			"",
+			map[string]string{"status": "0"},
+		},
+		{
+			"read file",
+			fmt.Sprintf("def check():\n    reason = read_file(%q)\n    if reason != None:\n        fail(\"in maintenance: \" + reason.strip())\n    if read_file(%q) != None:\n        fail(\"read a missing file\")\n", maintenance, filepath.Join(dir, "missing")),
+			"in maintenance: upgrading",
+			"",
+			nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		program, err := options.CompileStarlark("check.star", []byte(testCase.source))
+		if !assert.Nil(t, err, testCase.name) {
+			continue
+		}
+		check := options.StarlarkCheck{Name: "check", Path: "check.star", Program: program, MaxSteps: 10000, Timeout: defaultCheckTimeout}
+		result := runStarlark(check, opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+		}
+		if testCase.expectedWarning == "" {
+			assert.Nil(t, result.Warning, testCase.name)
+		} else if assert.NotNil(t, result.Warning, testCase.name) {
+			assert.Equal(t, testCase.expectedWarning, result.Warning.Error(), testCase.name)
+		}
+		for name, value := range testCase.expectedDetails {
+			assert.Equal(t, value, result.Details[name], testCase.name)
+		}
+	}
+}
+
+func TestRunStarlarkTimeout(t *testing.T) {
+	t.Parallel()
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	// The server never responds, so the script is stuck in http_get until the timeout, which either cancels the request
+	// or the interpreter, depending on which notices first
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	source := fmt.Sprintf("def check():\n    response = http_get(%q)\n    if not response.ok:\n        fail(response.error)\n", server.URL)
+	program, err := options.CompileStarlark("check.star", []byte(source))
+	if err != nil {
+		assert.FailNow(t, "Failed to compile script: %v", err.Error())
+	}
+	check := options.StarlarkCheck{Name: "check", Path: "check.star", Program: program, MaxSteps: 10000, Timeout: time.Second}
+
+	start := time.Now()
+	result := runStarlark(check, opts)
+	if assert.NotNil(t, result.Err) {
+		assert.Regexp(t, "the script did not finish within 1s|context deadline exceeded", result.Err.Error())
+	}
+	assert.True(t, time.Since(start) < defaultCheckTimeout)
+}
+
+func TestRunStarlarkTwice(t *testing.T) {
+	t.Parallel()
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	// The program is compiled once, and each run gets its own globals, which are frozen before check() is called
+	source := "runs = []\nruns.append(1)\ndef check():\n    detail(\"runs\", len(runs))\n"
+	program, err := options.CompileStarlark("check.star", []byte(source))
+	if err != nil {
+		assert.FailNow(t, "Failed to compile script: %v", err.Error())
+	}
+	check := options.StarlarkCheck{Name: "check", Path: "check.star", Program: program, MaxSteps: 10000, Timeout: defaultCheckTimeout}
+
+	for i := 0; i < 2; i++ {
+		result := runStarlark(check, opts)
+		assert.Nil(t, result.Err)
+		assert.Equal(t, "1", result.Details["runs"])
+	}
+}
+
+func TestStarlarkBuiltinsMatchOptions(t *testing.T) {
+	t.Parallel()
+
+	// Options compiles scripts against the names in options.StarlarkBuiltins, so every one of them must be defined here
+	builtins := starlarkBuiltins(context.Background(), &checkResult{Details: map[string]string{}})
+	assert.Equal(t, len(options.StarlarkBuiltins), len(builtins))
+	for _, name := range options.StarlarkBuiltins {
+		assert.True(t, builtins.Has(name), name)
+	}
+}
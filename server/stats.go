@@ -0,0 +1,195 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// The suffix of the rate of a counter, such as evictions_per_second for evictions
+const statRateSuffix = "_per_second"
+
+// The checks are rebuilt for every request, so the statistics from the previous run of each check, which are needed to
+// compute the rate of counters, are kept here instead. They are keyed by the position of the check on the command line,
+// because two checks of the same daemon run at the same time, and must not compute rates from each other's samples.
+var statsSamples = struct {
+	sync.Mutex
+	byCheck map[string]statsSample
+}{byCheck: map[string]statsSample{}}
+
+type statsSample struct {
+	values map[string]float64
+	time   time.Time
+}
+
+// Check the statistics that a daemon such as memcached reports in reply to a command
+type statsCheck struct {
+	stats options.StatsCheck
+	// The position of the check among the checks of the same protocol, which identifies its samples
+	index int
+	opts  *options.Options
+}
+
+func (c *statsCheck) Name() string {
+	if c.stats.Protocol == "memcached" {
+		return fmt.Sprintf("Memcached check of %s", c.stats.Address)
+	}
+	return fmt.Sprintf("Stats check of %s", c.stats.Address)
+}
+
+func (c *statsCheck) Run() *checkResult {
+	c.opts.Logger.Infof("Attempting to read stats from %s...", c.stats.Address)
+
+	stats, err := readStats(c.stats)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+	return evaluateStats(fmt.Sprintf("%s\x00%d", c.stats.Protocol, c.index), c.stats, stats, time.Now())
+}
+
+// Send the command and parse the statistics in the reply
+func readStats(check options.StatsCheck) (map[string]string, error) {
+	conn, err := net.DialTimeout("tcp", check.Address, defaultCheckTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(defaultCheckTimeout))
+
+	if len(check.Payload) > 0 {
+		if _, err := conn.Write(check.Payload); err != nil {
+			return nil, err
+		}
+	}
+
+	stats := map[string]string{}
+	reader := bufio.NewReader(io.LimitReader(conn, maxResponseSize))
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF && line == "" {
+			if check.End != "" {
+				return nil, IncompleteStatsReply(check.End)
+			}
+			return stats, nil
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if check.End != "" && line == check.End {
+			return stats, nil
+		}
+
+		if check.Prefix != "" {
+			if !strings.HasPrefix(line, check.Prefix) {
+				return nil, UnexpectedStatsLine{prefix: check.Prefix, line: line}
+			}
+			line = strings.TrimPrefix(line, check.Prefix)
+		}
+
+		// Lines without a value, such as blank lines or section headers, are skipped
+		name, value, ok := splitStat(line, check.Separator)
+		if ok {
+			stats[name] = value
+		}
+	}
+}
+
+func splitStat(line string, separator string) (string, string, bool) {
+	var nameAndValue []string
+	if separator == "" {
+		nameAndValue = strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(nameAndValue) != 2 {
+			nameAndValue = strings.SplitN(strings.TrimSpace(line), "\t", 2)
+		}
+	} else {
+		nameAndValue = strings.SplitN(line, separator, 2)
+	}
+	if len(nameAndValue) != 2 || strings.TrimSpace(nameAndValue[0]) == "" {
+		return "", "", false
+	}
+	return strings.TrimSpace(nameAndValue[0]), strings.TrimSpace(nameAndValue[1]), true
+}
+
+// Evaluate the assertions of the check against the statistics read at now. Numeric statistics are compared as numbers,
+// and the rate of each counter since the previous run of the check with the given key is added with a _per_second
+// suffix. Assertions about a rate that is unknown are skipped.
+func evaluateStats(key string, check options.StatsCheck, stats map[string]string, now time.Time) *checkResult {
+	document := map[string]interface{}{}
+	values := map[string]float64{}
+	for name, value := range stats {
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			document[name] = number
+			values[name] = number
+		} else {
+			document[name] = value
+		}
+	}
+
+	statsSamples.Lock()
+	previous := statsSamples.byCheck[key]
+	statsSamples.byCheck[key] = statsSample{values: values, time: now}
+	statsSamples.Unlock()
+
+	// The rate of a counter is unknown the first time a check runs, and when the counter was reset, such as by a restart
+	unknownRates := map[string]bool{}
+	for name, value := range values {
+		previousValue, ok := previous.values[name]
+		if !ok || value < previousValue || !now.After(previous.time) {
+			unknownRates[name+statRateSuffix] = true
+			continue
+		}
+		document[name+statRateSuffix] = (value - previousValue) / now.Sub(previous.time).Seconds()
+	}
+
+	assertions := []options.JsonAssertion{}
+	result := &checkResult{Details: map[string]string{}}
+	for _, assertion := range check.Assertions {
+		if unknownRates[assertion.Path] {
+			continue
+		}
+		if value, found := document[assertion.Path]; found {
+			result.Details[assertion.Path] = formatJsonValue(value)
+		}
+		assertions = append(assertions, assertion)
+	}
+
+	result.Err, result.Warning = evaluateJsonAssertions(document, assertions)
+	if result.Err == nil && len(stats) == 0 {
+		result.Err = NoStatsReported(check.Address)
+	}
+	return result
+}
+
+// Custom error types
+
+type UnexpectedStatsLine struct {
+	prefix string
+	line   string
+}
+
+func (err UnexpectedStatsLine) Error() string {
+	return fmt.Sprintf("expected a line starting with %q but got %q", err.prefix, err.line)
+}
+
+type IncompleteStatsReply string
+
+func (end IncompleteStatsReply) Error() string {
+	return fmt.Sprintf("the reply ended without %q", string(end))
+}
+
+type NoStatsReported string
+
+func (address NoStatsReported) Error() string {
+	return fmt.Sprintf("%s did not report any statistics", string(address))
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/gruntwork-io/health-checker/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadStats(t *testing.T) {
+	// Will *not* run parallel because we're opening random tcp ports
+	// and want to avoid port clashes
+	ports, err := test.GetFreePorts(3)
+	if err != nil {
+		assert.FailNow(t, "Failed to get free ports: %v", err.Error())
+	}
+	memcachedAddress := test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[0])
+	brokenAddress := test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[1])
+	haproxyAddress := test.ListenerString(test.DEFAULT_LISTENER_ADDRESS, ports[2])
+
+	// Like the real daemons, memcached keeps the connection open after its reply, and HAProxy closes it
+	servers := []struct {
+		address string
+		reply   string
+		hangUp  bool
+	}{
+		{memcachedAddress, "STAT pid 1\r\nSTAT version 1.6.9\r\nSTAT curr_connections 10\r\nSTAT max_connections 1024\r\nEND\r\n", false},
+		{brokenAddress, "STAT pid 1\r\nSERVER_ERROR out of memory\r\n", false},
+		{haproxyAddress, "Name: HAProxy\nVersion: 2.8.3\nMaxconn: 4096\nCurrConns: 12\n\n", true},
+	}
+	for _, server := range servers {
+		l, err := net.Listen("tcp", server.address)
+		if err != nil {
+			assert.FailNow(t, "Failed to start listening: %s", err.Error())
+		}
+		defer l.Close()
+		go serveStats(l, server.reply, server.hangUp)
+	}
+
+	testCases := []struct {
+		name          string
+		stats         options.StatsCheck
+		expectedErr   string
+		expectedStats map[string]string
+	}{
+		{
+			"memcached",
+			options.StatsCheck{Address: memcachedAddress, Payload: []byte("stats\r\n"), Prefix: "STAT ", End: "END"},
+			"",
+			map[string]string{"pid": "1", "version": "1.6.9", "curr_connections": "10", "max_connections": "1024"},
+		},
+		{
+			"memcached error",
+			options.StatsCheck{Address: brokenAddress, Payload: []byte("stats\r\n"), Prefix: "STAT ", End: "END"},
+			`expected a line starting with "STAT " but got "SERVER_ERROR out of memory"`,
+			nil,
+		},
+		{
+			"missing end",
+			options.StatsCheck{Address: haproxyAddress, Payload: []byte("show info\n"), Separator: ":", End: "END"},
+			`the reply ended without "END"`,
+			nil,
+		},
+		{
+			"separator",
+			options.StatsCheck{Address: haproxyAddress, Payload: []byte("show info\n"), Separator: ":"},
+			"",
+			map[string]string{"Name": "HAProxy", "Version": "2.8.3", "Maxconn": "4096", "CurrConns": "12"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		stats, err := readStats(testCase.stats)
+		if testCase.expectedErr == "" {
+			if assert.Nil(t, err, testCase.name) {
+				assert.Equal(t, testCase.expectedStats, stats, testCase.name)
+			}
+		} else if assert.NotNil(t, err, testCase.name) {
+			assert.Equal(t, testCase.expectedErr, err.Error(), testCase.name)
+		}
+	}
+}
+
+func TestEvaluateStats(t *testing.T) {
+	t.Parallel()
+
+	assertions := func(expect []string, warn []string) []options.JsonAssertion {
+		rv := []options.JsonAssertion{}
+		for _, s := range expect {
+			assertion, err := options.ParseJsonAssertion(s, false)
+			assert.Nil(t, err, s)
+			rv = append(rv, assertion)
+		}
+		for _, s := range warn {
+			assertion, err := options.ParseJsonAssertion(s, true)
+			assert.Nil(t, err, s)
+			rv = append(rv, assertion)
+		}
+		return rv
+	}
+
+	check := options.StatsCheck{
+		Address:    "memcached.health-checker.test:11211",
+		Assertions: assertions([]string{"curr_connections < ${max_connections}"}, []string{"evictions_per_second < 50"}),
+	}
+	start := time.Now()
+
+	testCases := []struct {
+		name            string
+		stats           map[string]string
+		at              time.Time
+		expectedErr     string
+		expectedWarning string
+	}{
+		// There is no previous run to compute the rate of evictions from, so that assertion is skipped
+		{"first run", map[string]string{"curr_connections": "10", "max_connections": "1024", "evictions": "1000"}, start, "", ""},
+		{"evictions spike", map[string]string{"curr_connections": "10", "max_connections": "1024", "evictions": "1500"}, start.Add(5 * time.Second), "", "expected evictions_per_second < 50 but evictions_per_second is 100"},
+		{"evictions calm down", map[string]string{"curr_connections": "10", "max_connections": "1024", "evictions": "1510"}, start.Add(10 * time.Second), "", ""},
+		{"too many connections", map[string]string{"curr_connections": "1024", "max_connections": "1024", "evictions": "1510"}, start.Add(15 * time.Second), "expected curr_connections < ${max_connections} but curr_connections is 1024", ""},
+		// A restart resets the counters, so the rate of evictions is unknown
+		{"restart", map[string]string{"curr_connections": "1", "max_connections": "1024", "evictions": "0"}, start.Add(20 * time.Second), "", ""},
+		{"empty", map[string]string{}, start.Add(25 * time.Second), "expected curr_connections < ${max_connections} but curr_connections is missing", "expected evictions_per_second < 50 but evictions_per_second is missing"},
+	}
+
+	for _, testCase := range testCases {
+		result := evaluateStats("TestEvaluateStats", check, testCase.stats, testCase.at)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Equal(t, testCase.expectedErr, result.Err.Error(), testCase.name)
+		}
+		if testCase.expectedWarning == "" {
+			assert.Nil(t, result.Warning, testCase.name)
+		} else if assert.NotNil(t, result.Warning, testCase.name) {
+			assert.Equal(t, testCase.expectedWarning, result.Warning.Error(), testCase.name)
+		}
+	}
+}
+
+func TestEvaluateStatsKeepsSamplesPerCheck(t *testing.T) {
+	t.Parallel()
+
+	assertion, err := options.ParseJsonAssertion("evictions_per_second < 50", true)
+	assert.Nil(t, err)
+	check := options.StatsCheck{Address: "memcached.health-checker.test:11211", Payload: []byte("stats\r\n"), Assertions: []options.JsonAssertion{assertion}}
+	start := time.Now()
+
+	// Two checks of the same daemon run at the same time, so each must compute its rate from its own previous run rather
+	// than from the other check's sample a millisecond earlier
+	evaluateStats("TestEvaluateStatsKeepsSamplesPerCheck\x000", check, map[string]string{"evictions": "1000"}, start)
+	evaluateStats("TestEvaluateStatsKeepsSamplesPerCheck\x001", check, map[string]string{"evictions": "1000"}, start.Add(time.Millisecond))
+	first := evaluateStats("TestEvaluateStatsKeepsSamplesPerCheck\x000", check, map[string]string{"evictions": "1100"}, start.Add(5*time.Second))
+	second := evaluateStats("TestEvaluateStatsKeepsSamplesPerCheck\x001", check, map[string]string{"evictions": "1101"}, start.Add(5*time.Second+time.Millisecond))
+
+	assert.Nil(t, first.Warning)
+	assert.Nil(t, second.Warning)
+	assert.Equal(t, "20.2", second.Details["evictions_per_second"])
+}
+
+// Accept connections on l, read the command and send reply. Unless hangUp is set, the connection stays open until the
+// client closes it, like a daemon waiting for the next command.
+func serveStats(l net.Listener, reply string, hangUp bool) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+			conn.Read(make([]byte, 1024))
+			conn.Write([]byte(reply))
+			if !hangUp {
+				io.Copy(ioutil.Discard, conn)
+			}
+		}(conn)
+	}
+}
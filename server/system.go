@@ -0,0 +1,216 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// Check the load average in /proc/loadavg
+type loadCheck struct {
+	load options.LoadCheck
+	opts *options.Options
+}
+
+func (c *loadCheck) Name() string {
+	return "Load average"
+}
+
+func (c *loadCheck) Run() *checkResult {
+	c.opts.Logger.Infof("Attempting to read the load average from %s...", c.opts.ProcRoot)
+
+	loads, err := readLoadAverage(c.opts.ProcRoot)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	result := &checkResult{}
+
+	if c.load.PerCpu {
+		cpus, err := countCpus(c.opts.ProcRoot)
+		if err != nil {
+			return &checkResult{Err: err}
+		}
+		for i := range loads {
+			loads[i] /= float64(cpus)
+		}
+		result.addDetail("cpus", float64(cpus))
+	}
+
+	result.checkMaximum("load1", loads[0], c.load.Load1)
+	result.checkMaximum("load5", loads[1], c.load.Load5)
+	result.checkMaximum("load15", loads[2], c.load.Load15)
+
+	return result
+}
+
+// Check the memory and swap usage in /proc/meminfo
+type memoryCheck struct {
+	memory options.MemoryCheck
+	opts   *options.Options
+}
+
+func (c *memoryCheck) Name() string {
+	return "Memory usage"
+}
+
+func (c *memoryCheck) Run() *checkResult {
+	c.opts.Logger.Infof("Attempting to read memory usage from %s...", c.opts.ProcRoot)
+
+	meminfo, err := readMeminfo(c.opts.ProcRoot)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	result := &checkResult{}
+
+	if meminfo["MemTotal"] > 0 {
+		result.checkMinimum("available_percent", meminfo["MemAvailable"]*100/meminfo["MemTotal"], c.memory.AvailablePercent)
+	}
+
+	swapUsedPercent := 0.0
+	if meminfo["SwapTotal"] > 0 {
+		swapUsedPercent = (meminfo["SwapTotal"] - meminfo["SwapFree"]) * 100 / meminfo["SwapTotal"]
+	}
+	result.checkMaximum("swap_used_percent", swapUsedPercent, c.memory.SwapUsedPercent)
+
+	return result
+}
+
+// Check the pressure stall information for a resource in /proc/pressure
+type pressureCheck struct {
+	pressure options.PressureCheck
+	opts     *options.Options
+}
+
+func (c *pressureCheck) Name() string {
+	return fmt.Sprintf("%s pressure (%s %s)", c.pressure.Resource, c.pressure.Kind, c.pressure.Window)
+}
+
+func (c *pressureCheck) Run() *checkResult {
+	c.opts.Logger.Infof("Attempting to read %s pressure from %s...", c.pressure.Resource, c.opts.ProcRoot)
+
+	stalled, err := readPressure(c.opts.ProcRoot, c.pressure.Resource, c.pressure.Kind, c.pressure.Window)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	result := &checkResult{}
+	result.checkMaximum(fmt.Sprintf("%s_%s", c.pressure.Kind, c.pressure.Window), stalled, c.pressure.Stalled)
+	return result
+}
+
+// Read the 1, 5 and 15 minute load averages from the first three fields of /proc/loadavg
+func readLoadAverage(procRoot string) ([]float64, error) {
+	path := filepath.Join(procRoot, "loadavg")
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(contents))
+	if len(fields) < 3 {
+		return nil, UnexpectedProcFormat(path)
+	}
+
+	loads := []float64{}
+	for _, field := range fields[:3] {
+		load, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, UnexpectedProcFormat(path)
+		}
+		loads = append(loads, load)
+	}
+	return loads, nil
+}
+
+// Count the processors listed in /proc/cpuinfo
+func countCpus(procRoot string) (int, error) {
+	file, err := os.Open(filepath.Join(procRoot, "cpuinfo"))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	cpus := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "processor") {
+			cpus++
+		}
+	}
+	if cpus == 0 {
+		return 0, UnexpectedProcFormat(file.Name())
+	}
+	return cpus, scanner.Err()
+}
+
+// Read /proc/meminfo into a map from field name to value. Values are in kB, as reported by the kernel.
+func readMeminfo(procRoot string) (map[string]float64, error) {
+	file, err := os.Open(filepath.Join(procRoot, "meminfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	meminfo := map[string]float64{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Lines look like "MemAvailable:   12345678 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		meminfo[strings.TrimSuffix(fields[0], ":")] = value
+	}
+	if _, ok := meminfo["MemAvailable"]; !ok {
+		return nil, UnexpectedProcFormat(file.Name())
+	}
+	return meminfo, scanner.Err()
+}
+
+// Read a single average from /proc/pressure/<resource>, whose lines look like
+// "some avg10=0.00 avg60=0.00 avg300=0.00 total=0"
+func readPressure(procRoot string, resource string, kind string, window string) (float64, error) {
+	path := filepath.Join(procRoot, "pressure", resource)
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != kind {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, window+"=") {
+				value, err := strconv.ParseFloat(strings.TrimPrefix(field, window+"="), 64)
+				if err != nil {
+					return 0, UnexpectedProcFormat(path)
+				}
+				return value, nil
+			}
+		}
+	}
+
+	return 0, UnexpectedProcFormat(path)
+}
+
+// Custom error types
+
+type UnexpectedProcFormat string
+
+func (path UnexpectedProcFormat) Error() string {
+	return fmt.Sprintf("could not parse %s", string(path))
+}
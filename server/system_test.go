@@ -0,0 +1,116 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+const testProcRoot = "testdata/proc"
+
+func TestSystemChecks(t *testing.T) {
+	t.Parallel()
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+	opts.ProcRoot = testProcRoot
+
+	// The fixtures report load averages of 3.20 2.50 1.75 on 2 CPUs, 10% of memory available, 25% of swap used, and
+	// pressure stall information for cpu and memory
+	testCases := []struct {
+		name            string
+		check           check
+		expectedErr     string
+		expectedWarning string
+	}{
+		{
+			"load within thresholds",
+			&loadCheck{load: options.LoadCheck{Load1: options.Thresholds{Warn: 4, Fail: 8}}, opts: opts},
+			"",
+			"",
+		},
+		{
+			"load1 warning",
+			&loadCheck{load: options.LoadCheck{Load1: options.Thresholds{Warn: 3, Fail: 8}}, opts: opts},
+			"",
+			"load1 is 3.20, which is more than 3.00",
+		},
+		{
+			"load15 failure",
+			&loadCheck{load: options.LoadCheck{Load15: options.Thresholds{Fail: 1.5}}, opts: opts},
+			"load15 is 1.75, which is more than 1.50",
+			"",
+		},
+		{
+			"load per cpu",
+			&loadCheck{load: options.LoadCheck{Load1: options.Thresholds{Fail: 1.5}, PerCpu: true}, opts: opts},
+			"load1 is 1.60, which is more than 1.50",
+			"",
+		},
+		{
+			"memory within thresholds",
+			&memoryCheck{memory: options.MemoryCheck{AvailablePercent: options.Thresholds{Fail: 5}}, opts: opts},
+			"",
+			"",
+		},
+		{
+			"memory available warning",
+			&memoryCheck{memory: options.MemoryCheck{AvailablePercent: options.Thresholds{Warn: 15, Fail: 5}}, opts: opts},
+			"",
+			"available_percent is 10.00, which is less than 15.00",
+		},
+		{
+			"swap used failure",
+			&memoryCheck{memory: options.MemoryCheck{SwapUsedPercent: options.Thresholds{Fail: 20}}, opts: opts},
+			"swap_used_percent is 25.00, which is more than 20.00",
+			"",
+		},
+		{
+			"pressure within thresholds",
+			&pressureCheck{pressure: options.PressureCheck{Resource: "memory", Kind: "some", Window: "avg10", Stalled: options.Thresholds{Warn: 20, Fail: 50}}, opts: opts},
+			"",
+			"",
+		},
+		{
+			"pressure failure",
+			&pressureCheck{pressure: options.PressureCheck{Resource: "cpu", Kind: "some", Window: "avg60", Stalled: options.Thresholds{Warn: 10, Fail: 30}}, opts: opts},
+			"some_avg60 is 40.00, which is more than 30.00",
+			"some_avg60 is 40.00, which is more than 10.00",
+		},
+		{
+			"full pressure warning",
+			&pressureCheck{pressure: options.PressureCheck{Resource: "memory", Kind: "full", Window: "avg60", Stalled: options.Thresholds{Warn: 1}}, opts: opts},
+			"",
+			"full_avg60 is 1.50, which is more than 1.00",
+		},
+	}
+
+	for _, testCase := range testCases {
+		result := testCase.check.Run()
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Equal(t, testCase.expectedErr, result.Err.Error(), testCase.name)
+		}
+		if testCase.expectedWarning == "" {
+			assert.Nil(t, result.Warning, testCase.name)
+		} else if assert.NotNil(t, result.Warning, testCase.name) {
+			assert.Equal(t, testCase.expectedWarning, result.Warning.Error(), testCase.name)
+		}
+	}
+}
+
+func TestSystemChecksMissingProcRoot(t *testing.T) {
+	t.Parallel()
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+	opts.ProcRoot = "testdata/does-not-exist"
+
+	for _, c := range []check{
+		&loadCheck{opts: opts},
+		&memoryCheck{opts: opts},
+		&pressureCheck{pressure: options.PressureCheck{Resource: "io", Kind: "some", Window: "avg10"}, opts: opts},
+	} {
+		assert.NotNil(t, c.Run().Err, c.Name())
+	}
+}
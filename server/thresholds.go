@@ -0,0 +1,54 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// Compare a measured value to the maximums in thresholds. The first failure and the first warning are recorded in the
+// result, and the value is added to its details.
+func (result *checkResult) checkMaximum(name string, value float64, thresholds options.Thresholds) {
+	result.addDetail(name, value)
+	if thresholds.Fail > 0 && value > thresholds.Fail && result.Err == nil {
+		result.Err = ThresholdCrossed{name: name, value: value, threshold: thresholds.Fail, maximum: true}
+	}
+	if thresholds.Warn > 0 && value > thresholds.Warn && result.Warning == nil {
+		result.Warning = ThresholdCrossed{name: name, value: value, threshold: thresholds.Warn, maximum: true}
+	}
+}
+
+// Compare a measured value to the minimums in thresholds. The first failure and the first warning are recorded in the
+// result, and the value is added to its details.
+func (result *checkResult) checkMinimum(name string, value float64, thresholds options.Thresholds) {
+	result.addDetail(name, value)
+	if thresholds.Fail > 0 && value < thresholds.Fail && result.Err == nil {
+		result.Err = ThresholdCrossed{name: name, value: value, threshold: thresholds.Fail}
+	}
+	if thresholds.Warn > 0 && value < thresholds.Warn && result.Warning == nil {
+		result.Warning = ThresholdCrossed{name: name, value: value, threshold: thresholds.Warn}
+	}
+}
+
+func (result *checkResult) addDetail(name string, value float64) {
+	if result.Details == nil {
+		result.Details = map[string]string{}
+	}
+	result.Details[name] = fmt.Sprintf("%.2f", value)
+}
+
+// Custom error types
+
+type ThresholdCrossed struct {
+	name      string
+	value     float64
+	threshold float64
+	maximum   bool
+}
+
+func (err ThresholdCrossed) Error() string {
+	if err.maximum {
+		return fmt.Sprintf("%s is %.2f, which is more than %.2f", err.name, err.value, err.threshold)
+	}
+	return fmt.Sprintf("%s is %.2f, which is less than %.2f", err.name, err.value, err.threshold)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// Check that a TLS handshake succeeds and that the server's certificate isn't about to expire
+type tlsCheck struct {
+	tls  options.TlsCheck
+	opts *options.Options
+}
+
+func (c *tlsCheck) Name() string {
+	return fmt.Sprintf("TLS handshake with %s", c.tls.Address)
+}
+
+func (c *tlsCheck) Run() *checkResult {
+	return attemptTlsHandshake(c.tls, c.opts)
+}
+
+// Perform a TLS handshake, verifying the certificate chain, and report on the leaf certificate's expiry
+func attemptTlsHandshake(check options.TlsCheck, opts *options.Options) *checkResult {
+	logger := opts.Logger
+	logger.Infof("Attempting a TLS handshake with %s as %s...", check.Address, check.ServerName)
+
+	dialer := &net.Dialer{Timeout: defaultCheckTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", check.Address, &tls.Config{
+		ServerName: check.ServerName,
+		RootCAs:    check.RootCAs,
+	})
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	defer conn.Close()
+
+	leaf := conn.ConnectionState().PeerCertificates[0]
+	daysToExpiry := int(time.Until(leaf.NotAfter).Hours() / 24)
+
+	result := &checkResult{Details: certificateDetails(leaf, daysToExpiry)}
+
+	switch {
+	case daysToExpiry < check.FailDays:
+		result.Err = CertificateExpiresSoon{daysToExpiry: daysToExpiry, threshold: check.FailDays}
+	case daysToExpiry < check.WarnDays:
+		result.Warning = CertificateExpiresSoon{daysToExpiry: daysToExpiry, threshold: check.WarnDays}
+	}
+
+	return result
+}
+
+func certificateDetails(cert *x509.Certificate, daysToExpiry int) map[string]string {
+	return map[string]string{
+		"subject":        cert.Subject.String(),
+		"issuer":         cert.Issuer.String(),
+		"not_after":      cert.NotAfter.UTC().Format(time.RFC3339),
+		"days_to_expiry": strconv.Itoa(daysToExpiry),
+	}
+}
+
+// Custom error types
+
+type CertificateExpiresSoon struct {
+	daysToExpiry int
+	threshold    int
+}
+
+func (err CertificateExpiresSoon) Error() string {
+	return fmt.Sprintf("certificate expires in %d days, which is within the %d day threshold", err.daysToExpiry, err.threshold)
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptTlsHandshake(t *testing.T) {
+	t.Parallel()
+
+	caCert, caKey := createTestCertificate(t, "Test CA", 365*24*time.Hour, nil, nil)
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(caCert)
+
+	healthy := startTlsServer(t, caCert, caKey, 90*24*time.Hour)
+	expiringSoon := startTlsServer(t, caCert, caKey, 10*24*time.Hour+time.Hour)
+
+	testCases := []struct {
+		name            string
+		tls             options.TlsCheck
+		expectedErr     string
+		expectedWarning string
+	}{
+		{
+			"valid certificate",
+			options.TlsCheck{Address: healthy, ServerName: "localhost", RootCAs: rootCAs, WarnDays: 30, FailDays: 7},
+			"",
+			"",
+		},
+		{
+			"unknown CA",
+			options.TlsCheck{Address: healthy, ServerName: "localhost", WarnDays: 30, FailDays: 7},
+			"certificate signed by unknown authority",
+			"",
+		},
+		{
+			"wrong server name",
+			options.TlsCheck{Address: healthy, ServerName: "example.com", RootCAs: rootCAs},
+			"certificate is valid for localhost, not example.com",
+			"",
+		},
+		{
+			"expires within warning window",
+			options.TlsCheck{Address: expiringSoon, ServerName: "localhost", RootCAs: rootCAs, WarnDays: 30, FailDays: 7},
+			"",
+			"certificate expires in 10 days, which is within the 30 day threshold",
+		},
+		{
+			"expires within failure window",
+			options.TlsCheck{Address: expiringSoon, ServerName: "localhost", RootCAs: rootCAs, WarnDays: 30, FailDays: 14},
+			"certificate expires in 10 days, which is within the 14 day threshold",
+			"",
+		},
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	for _, testCase := range testCases {
+		result := attemptTlsHandshake(testCase.tls, opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+			assert.Equal(t, "CN=localhost", result.Details["subject"], testCase.name)
+			assert.Equal(t, "CN=Test CA", result.Details["issuer"], testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+		}
+		if testCase.expectedWarning == "" {
+			assert.Nil(t, result.Warning, testCase.name)
+		} else if assert.NotNil(t, result.Warning, testCase.name) {
+			assert.Contains(t, result.Warning.Error(), testCase.expectedWarning, testCase.name)
+		}
+	}
+}
+
+// Start a TLS server for localhost whose certificate is signed by the given CA and expires after the given duration.
+// Returns the address of the server.
+func startTlsServer(t *testing.T, caCert *x509.Certificate, caKey *ecdsa.PrivateKey, validFor time.Duration) string {
+	cert, key := createTestCertificate(t, "localhost", validFor, caCert, caKey)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{cert.Raw}, PrivateKey: key}},
+	})
+	if err != nil {
+		assert.FailNow(t, "Failed to start TLS server: %s", err.Error())
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}(conn)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+// Create a certificate for the given common name, signed by the given parent. If parent is nil, the certificate is a
+// self-signed CA.
+func createTestCertificate(t *testing.T, commonName string, validFor time.Duration, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		assert.FailNow(t, "Failed to generate key: %s", err.Error())
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validFor),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent = template
+		parentKey = key
+	} else {
+		template.DNSNames = []string{commonName}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		assert.FailNow(t, "Failed to create certificate: %s", err.Error())
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		assert.FailNow(t, "Failed to parse certificate: %s", err.Error())
+	}
+
+	return cert, key
+}
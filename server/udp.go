@@ -0,0 +1,88 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// When a UDP check doesn't expect a response, we still wait this long for an ICMP port unreachable message so that a
+// closed port is reported as a failure
+const udpUnreachableGracePeriod = time.Millisecond * 250
+
+// Check that a UDP port accepts a datagram, and optionally that it replies with an expected response
+type udpCheck struct {
+	udp  options.UdpCheck
+	opts *options.Options
+}
+
+func (c *udpCheck) Name() string {
+	return fmt.Sprintf("UDP probe of %s", c.udp.Address)
+}
+
+func (c *udpCheck) Run() *checkResult {
+	return &checkResult{Err: attemptUdpProbe(c.udp, c.opts)}
+}
+
+// Send the payload in the given check and wait for a response, if one is expected
+func attemptUdpProbe(udp options.UdpCheck, opts *options.Options) error {
+	logger := opts.Logger
+	logger.Infof("Attempting to send a %d byte payload to %s via UDP...", len(udp.Payload), udp.Address)
+
+	conn, err := net.DialTimeout("udp", udp.Address, defaultCheckTimeout)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	readTimeout := udpUnreachableGracePeriod
+	if udp.ExpectsResponse() {
+		readTimeout = defaultCheckTimeout
+	}
+	conn.SetDeadline(time.Now().Add(readTimeout))
+
+	if _, err := conn.Write(udp.Payload); err != nil {
+		return udpError(udp.Address, err)
+	}
+
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		var netErr net.Error
+		if !udp.ExpectsResponse() && errors.As(err, &netErr) && netErr.Timeout() {
+			// No news is good news: nothing told us the port is closed
+			return nil
+		}
+		return udpError(udp.Address, err)
+	}
+
+	response := buf[:n]
+	logger.Debugf("Received %d byte response from %s: %q", n, udp.Address, response)
+
+	if !udp.MatchesResponse(response) {
+		return UnexpectedResponse{expected: udp.DescribeExpectedResponse(), actual: string(response)}
+	}
+
+	return nil
+}
+
+// The kernel reports an ICMP port unreachable message as a refused connection, which is confusing for UDP
+func udpError(address string, err error) error {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return UdpPortUnreachable(address)
+	}
+	return err
+}
+
+// Custom error types
+
+type UdpPortUnreachable string
+
+func (address UdpPortUnreachable) Error() string {
+	return fmt.Sprintf("%s is unreachable: received ICMP port unreachable, so nothing is listening on that port", string(address))
+}
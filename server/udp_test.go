@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net"
+	"regexp"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptUdpProbe(t *testing.T) {
+	t.Parallel()
+
+	// A UDP server that replies to every datagram with the datagram prefixed with "PONG "
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		assert.FailNow(t, "Failed to start UDP server: %s", err.Error())
+	}
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteTo([]byte("PONG "+string(buf[:n])), addr)
+		}
+	}()
+
+	// Grab a free UDP port and close it again, so that nothing is listening on it
+	closed, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		assert.FailNow(t, "Failed to find a free UDP port: %s", err.Error())
+	}
+	closedAddress := closed.LocalAddr().String()
+	closed.Close()
+
+	testCases := []struct {
+		name        string
+		udp         options.UdpCheck
+		expectedErr string
+	}{
+		{
+			"send only",
+			options.UdpCheck{Address: conn.LocalAddr().String(), Payload: []byte("ping")},
+			"",
+		},
+		{
+			"expected response",
+			options.UdpCheck{Address: conn.LocalAddr().String(), Payload: []byte("ping"), Expect: "PONG"},
+			"",
+		},
+		{
+			"expected response is not a pattern",
+			options.UdpCheck{Address: conn.LocalAddr().String(), Payload: []byte("ping"), Expect: "^PONG"},
+			`expected a response containing "^PONG" but got "PONG ping"`,
+		},
+		{
+			"response matching pattern",
+			options.UdpCheck{Address: conn.LocalAddr().String(), Payload: []byte("ping"), ExpectRegex: regexp.MustCompile("^PONG ping$")},
+			"",
+		},
+		{
+			"unexpected response",
+			options.UdpCheck{Address: conn.LocalAddr().String(), Payload: []byte("ping"), ExpectRegex: regexp.MustCompile("^OK")},
+			`expected a response matching "^OK" but got "PONG ping"`,
+		},
+		{
+			"port unreachable",
+			options.UdpCheck{Address: closedAddress, Payload: []byte("ping")},
+			"received ICMP port unreachable",
+		},
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	for _, testCase := range testCases {
+		err := attemptUdpProbe(testCase.udp, opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err, testCase.name)
+		} else if assert.NotNil(t, err, testCase.name) {
+			assert.Contains(t, err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// Check that we can connect to a Unix domain socket
+type unixCheck struct {
+	unix options.UnixCheck
+	opts *options.Options
+}
+
+func (c *unixCheck) Name() string {
+	return fmt.Sprintf("Unix socket connection to %s", c.unix.Path)
+}
+
+func (c *unixCheck) Run() *checkResult {
+	return &checkResult{Err: attemptUnixConnection(c.unix, c.opts)}
+}
+
+// Connect to the socket in the given check. Go treats a path starting with @ as a socket in the abstract namespace.
+func attemptUnixConnection(unix options.UnixCheck, opts *options.Options) error {
+	opts.Logger.Infof("Attempting to connect to Unix socket %s...", unix.Path)
+
+	conn, err := net.DialTimeout("unix", unix.Path, defaultCheckTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
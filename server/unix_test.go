@@ -0,0 +1,158 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptUnixConnection(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("Abstract Unix sockets are only supported on Linux")
+	}
+
+	dir, err := ioutil.TempDir("", "health-checker-unix-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.sock")
+	abstract := fmt.Sprintf("@health-checker-test-%d", os.Getpid())
+
+	for _, address := range []string{path, abstract} {
+		l, err := net.Listen("unix", address)
+		if err != nil {
+			assert.FailNow(t, "Failed to start listening: %s", err.Error())
+		}
+		defer l.Close()
+		go handleRequests(t, l, nil)
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	testCases := []struct {
+		name        string
+		path        string
+		expectedErr string
+	}{
+		{"socket file", path, ""},
+		{"abstract socket", abstract, ""},
+		{"missing socket", filepath.Join(dir, "missing.sock"), "no such file or directory"},
+		{"missing abstract socket", abstract + "-missing", "connection refused"},
+	}
+
+	for _, testCase := range testCases {
+		err := attemptUnixConnection(options.UnixCheck{Path: testCase.path}, opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err, testCase.name)
+		} else if assert.NotNil(t, err, testCase.name) {
+			assert.Contains(t, err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestAttemptHttpRequestOverUnixSocket(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("Abstract Unix sockets are only supported on Linux")
+	}
+
+	dir, err := ioutil.TempDir("", "health-checker-unix-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "docker.sock")
+	abstract := fmt.Sprintf("@health-checker-http-test-%d", os.Getpid())
+
+	// A fake Docker Engine API that only answers pings
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_ping" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "OK")
+	})
+	for _, address := range []string{path, abstract} {
+		l, err := net.Listen("unix", address)
+		if err != nil {
+			assert.FailNow(t, "Failed to start listening: %s", err.Error())
+		}
+		defer l.Close()
+		go http.Serve(l, handler)
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	testCases := []struct {
+		name        string
+		socket      string
+		url         string
+		expectedErr string
+	}{
+		{"socket file", path, "http://localhost/_ping", ""},
+		{"abstract socket", abstract, "http://docker/_ping", ""},
+		{"not found", path, "http://localhost/missing", "unexpected HTTP status 404 Not Found"},
+		{"missing socket", filepath.Join(dir, "missing.sock"), "http://localhost/_ping", "no such file or directory"},
+	}
+
+	for _, testCase := range testCases {
+		check := options.HttpCheck{Url: testCase.url, UnixSocket: testCase.socket, Method: http.MethodGet, Timeout: defaultCheckTimeout}
+		result := attemptHttpRequest(check, opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+func TestHttpRequestsOverUnixSocketReuseConnections(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "health-checker-unix-test")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp dir: %v", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		assert.FailNow(t, "Failed to start listening: %s", err.Error())
+	}
+	defer l.Close()
+
+	connections := make(chan struct{}, 100)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "OK")
+		}),
+		ConnState: func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				connections <- struct{}{}
+			}
+		},
+	}
+	go server.Serve(l)
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+	check := options.HttpCheck{Url: "http://localhost/_ping", UnixSocket: path, Method: http.MethodGet, Timeout: defaultCheckTimeout}
+	for i := 0; i < 20; i++ {
+		assert.Nil(t, attemptHttpRequest(check, opts).Err)
+	}
+	assert.Equal(t, 1, len(connections))
+}
@@ -0,0 +1,320 @@
+package server
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// The GUID that the server appends to our key to prove that it understood the handshake, from RFC 6455
+const websocketGuid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// The opcodes of WebSocket frames
+const (
+	websocketContinuation = 0x0
+	websocketText         = 0x1
+	websocketBinary       = 0x2
+	websocketClose        = 0x8
+	websocketPing         = 0x9
+	websocketPong         = 0xa
+)
+
+// The status code of a close frame for a normal closure
+const websocketNormalClosure = 1000
+
+// Check that a server accepts WebSocket connections, which a plain HTTP check can't do, since a server that only serves
+// upgrades may reject a plain GET
+type websocketCheck struct {
+	websocket options.WebsocketCheck
+	opts      *options.Options
+}
+
+func (c *websocketCheck) Name() string {
+	return fmt.Sprintf("WebSocket %s", c.websocket.Url)
+}
+
+func (c *websocketCheck) Run() *checkResult {
+	return attemptWebsocketCheck(c.websocket, c.opts)
+}
+
+func attemptWebsocketCheck(check options.WebsocketCheck, opts *options.Options) *checkResult {
+	logger := opts.Logger
+	logger.Infof("Attempting a WebSocket handshake with %s...", check.Url)
+
+	u, err := url.Parse(check.Url)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	conn, err := dialWebsocket(u, check.Timeout)
+	if err != nil {
+		return &checkResult{Err: err}
+	}
+
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(check.Timeout))
+	reader := bufio.NewReader(conn)
+
+	if err := websocketHandshake(conn, reader, u, check.Origin); err != nil {
+		return &checkResult{Err: err}
+	}
+
+	if len(check.Payload) > 0 {
+		opcode := byte(websocketText)
+		if check.Binary {
+			opcode = websocketBinary
+		}
+		if err := writeWebsocketFrame(conn, opcode, check.Payload, true); err != nil {
+			return &checkResult{Err: err}
+		}
+	}
+
+	if check.ExpectsReply() {
+		message, err := readWebsocketMessage(conn, reader)
+		logger.Debugf("Received WebSocket message from %s: %q", check.Url, message)
+		if err != nil {
+			return &checkResult{Err: UnexpectedResponse{expected: check.DescribeExpectedReply(), actual: string(message), err: err}}
+		}
+		if !check.MatchesReply(message) {
+			return &checkResult{Err: UnexpectedResponse{expected: check.DescribeExpectedReply(), actual: string(message)}}
+		}
+	}
+
+	// Close the connection the way a well behaved client would, by sending a close frame and waiting for the server to
+	// send one back. A server that doesn't is still accepting connections, so this only degrades the check.
+	result := &checkResult{}
+	closing := make([]byte, 2)
+	binary.BigEndian.PutUint16(closing, websocketNormalClosure)
+	if err := writeWebsocketFrame(conn, websocketClose, closing, true); err != nil {
+		result.Warning = WebsocketNotClosed{err: err}
+		return result
+	}
+	for {
+		header, _, err := readWebsocketFrame(reader)
+		if err != nil {
+			result.Warning = WebsocketNotClosed{err: err}
+			return result
+		}
+		if header&0x0f == websocketClose {
+			return result
+		}
+	}
+}
+
+// Open a TCP connection, or a TLS connection for wss:// URLs, to the host in u
+func dialWebsocket(u *url.URL, timeout time.Duration) (net.Conn, error) {
+	address := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "wss" {
+			port = "443"
+		}
+		address = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if u.Scheme == "wss" {
+		return tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: u.Hostname()})
+	}
+	return dialer.Dial("tcp", address)
+}
+
+// Send the upgrade request and check that the server switched protocols and accepted our key
+func websocketHandshake(conn net.Conn, reader *bufio.Reader, u *url.URL, origin string) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	request := &strings.Builder{}
+	fmt.Fprintf(request, "GET %s HTTP/1.1\r\n", u.RequestURI())
+	fmt.Fprintf(request, "Host: %s\r\n", u.Host)
+	fmt.Fprintf(request, "Upgrade: websocket\r\nConnection: Upgrade\r\n")
+	fmt.Fprintf(request, "Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n", key)
+	if origin != "" {
+		fmt.Fprintf(request, "Origin: %s\r\n", origin)
+	}
+	request.WriteString("\r\n")
+	if _, err := io.WriteString(conn, request.String()); err != nil {
+		return err
+	}
+
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		return UnexpectedHttpStatus(response.StatusCode)
+	}
+	if !strings.EqualFold(response.Header.Get("Upgrade"), "websocket") {
+		return InvalidWebsocketHandshake(fmt.Sprintf("the Upgrade header is %q", response.Header.Get("Upgrade")))
+	}
+	if response.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		return InvalidWebsocketHandshake("the Sec-WebSocket-Accept header does not match our key")
+	}
+	return nil
+}
+
+// Return the value of Sec-WebSocket-Accept that proves the server saw the given key
+func websocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGuid))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// Read the next text or binary message, joining its fragments. Pings are answered with pongs while waiting.
+func readWebsocketMessage(conn net.Conn, reader *bufio.Reader) ([]byte, error) {
+	message := []byte{}
+	for {
+		header, payload, err := readWebsocketFrame(reader)
+		if err != nil {
+			return message, err
+		}
+
+		final := header&0x80 != 0
+		switch header & 0x0f {
+		case websocketPing:
+			if err := writeWebsocketFrame(conn, websocketPong, payload, true); err != nil {
+				return message, err
+			}
+		case websocketPong:
+		case websocketClose:
+			return message, WebsocketClosedByServer(payload)
+		case websocketText, websocketBinary, websocketContinuation:
+			message = append(message, payload...)
+			if len(message) > maxResponseSize {
+				return message, WebsocketMessageTooLarge(maxResponseSize)
+			}
+			if final {
+				return message, nil
+			}
+		}
+	}
+}
+
+// Read a frame, returning its first byte, which has the FIN bit and the opcode, and its unmasked payload
+func readWebsocketFrame(reader io.Reader) (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, nil, err
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(reader, extended); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err := io.ReadFull(reader, extended); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if length > maxResponseSize {
+		return 0, nil, WebsocketMessageTooLarge(maxResponseSize)
+	}
+
+	var mask []byte
+	if header[1]&0x80 != 0 {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(reader, mask); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range mask {
+		for j := i; j < len(payload); j += 4 {
+			payload[j] ^= mask[i]
+		}
+	}
+	return header[0], payload, nil
+}
+
+// Write a single, final frame. Frames sent by a client must be masked.
+func writeWebsocketFrame(writer io.Writer, opcode byte, payload []byte, masked bool) error {
+	frame := []byte{0x80 | opcode}
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, maskBit|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, maskBit|126, byte(len(payload)>>8), byte(len(payload)))
+	default:
+		extended := make([]byte, 8)
+		binary.BigEndian.PutUint64(extended, uint64(len(payload)))
+		frame = append(append(frame, maskBit|127), extended...)
+	}
+
+	body := append([]byte{}, payload...)
+	if masked {
+		mask := make([]byte, 4)
+		if _, err := rand.Read(mask); err != nil {
+			return err
+		}
+		frame = append(frame, mask...)
+		for i := range body {
+			body[i] ^= mask[i%4]
+		}
+	}
+
+	_, err := writer.Write(append(frame, body...))
+	return err
+}
+
+// Custom error types
+
+type InvalidWebsocketHandshake string
+
+func (reason InvalidWebsocketHandshake) Error() string {
+	return fmt.Sprintf("invalid WebSocket handshake: %s", string(reason))
+}
+
+type WebsocketClosedByServer []byte
+
+func (payload WebsocketClosedByServer) Error() string {
+	if len(payload) < 2 {
+		return "the server closed the connection"
+	}
+	return fmt.Sprintf("the server closed the connection with status %d %s", binary.BigEndian.Uint16(payload), string(payload[2:]))
+}
+
+type WebsocketMessageTooLarge int
+
+func (limit WebsocketMessageTooLarge) Error() string {
+	return fmt.Sprintf("the message is larger than %d bytes", int(limit))
+}
+
+type WebsocketNotClosed struct {
+	err error
+}
+
+func (err WebsocketNotClosed) Error() string {
+	return fmt.Sprintf("the server did not complete the closing handshake: %s", err.err)
+}
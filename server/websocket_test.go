@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptWebsocketCheck(t *testing.T) {
+	t.Parallel()
+
+	// A realtime gateway that only serves upgrades. It replies to every text message with a ping and then the message
+	// prefixed with "PONG ", split into two fragments. It replies to every binary message with a binary message that
+	// says whether it was binary, so that a text message sent by mistake fails the check.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" || r.Header.Get("Upgrade") != "websocket" {
+			http.Error(w, "WebSocket upgrades only", http.StatusBadRequest)
+			return
+		}
+
+		accept := websocketAccept(r.Header.Get("Sec-WebSocket-Key"))
+		if r.URL.Path == "/wrong-accept" {
+			accept = websocketAccept("wrong")
+		}
+
+		conn, buffer, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", accept)
+
+		reader := bufio.NewReader(buffer)
+		for {
+			header, payload, err := readWebsocketFrame(reader)
+			if err != nil {
+				return
+			}
+			switch {
+			case header&0x0f == websocketClose && r.URL.Path == "/no-close":
+				return
+			case header&0x0f == websocketClose:
+				writeWebsocketFrame(conn, websocketClose, payload, false)
+				return
+			case header&0x0f == websocketText && r.URL.Path == "/reject":
+				closing := make([]byte, 2)
+				binary.BigEndian.PutUint16(closing, 1008)
+				writeWebsocketFrame(conn, websocketClose, append(closing, "unauthorized"...), false)
+			case header&0x0f == websocketText:
+				writeWebsocketFrame(conn, websocketPing, []byte("are you there?"), false)
+				reply := []byte(fmt.Sprintf("PONG %s", payload))
+				conn.Write(append([]byte{websocketText, byte(5)}, reply[:5]...))
+				conn.Write(append([]byte{0x80 | websocketContinuation, byte(len(reply) - 5)}, reply[5:]...))
+			case header&0x0f == websocketBinary:
+				writeWebsocketFrame(conn, websocketBinary, append([]byte("BINARY "), payload...), false)
+			case header&0x0f == websocketPong && string(payload) != "are you there?":
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	url := "ws://" + server.Listener.Addr().String()
+
+	testCases := []struct {
+		name            string
+		websocket       options.WebsocketCheck
+		expectedErr     string
+		expectedWarning string
+	}{
+		{"handshake", options.WebsocketCheck{Url: url + "/socket"}, "", ""},
+		{"message", options.WebsocketCheck{Url: url + "/socket", Payload: []byte("ping"), Expect: "PONG ping"}, "", ""},
+		{"binary message", options.WebsocketCheck{Url: url + "/socket", Payload: []byte{0x00, 0xff}, Binary: true, Expect: "BINARY \x00\xff"}, "", ""},
+		{"regex", options.WebsocketCheck{Url: url + "/socket", Payload: []byte("ping"), ExpectRegex: regexp.MustCompile(`^PONG`)}, "", ""},
+		{"unexpected reply", options.WebsocketCheck{Url: url + "/socket", Payload: []byte("ping"), Expect: "pong"}, `expected a response containing "pong" but got "PONG ping"`, ""},
+		{"closed by server", options.WebsocketCheck{Url: url + "/reject", Payload: []byte("ping"), Expect: "PONG"}, "the server closed the connection with status 1008 unauthorized", ""},
+		{"wrong accept", options.WebsocketCheck{Url: url + "/wrong-accept"}, "invalid WebSocket handshake: the Sec-WebSocket-Accept header does not match our key", ""},
+		{"not closed", options.WebsocketCheck{Url: url + "/no-close"}, "", "the server did not complete the closing handshake: EOF"},
+		{"plain http", options.WebsocketCheck{Url: url + "/plain"}, "unexpected HTTP status 400 Bad Request", ""},
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	for _, testCase := range testCases {
+		testCase.websocket.Timeout = defaultCheckTimeout
+		result := attemptWebsocketCheck(testCase.websocket, opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, result.Err, testCase.name)
+		} else if assert.NotNil(t, result.Err, testCase.name) {
+			assert.Contains(t, result.Err.Error(), testCase.expectedErr, testCase.name)
+		}
+		if testCase.expectedWarning == "" {
+			assert.Nil(t, result.Warning, testCase.name)
+		} else if assert.NotNil(t, result.Warning, testCase.name) {
+			assert.Equal(t, testCase.expectedWarning, result.Warning.Error(), testCase.name)
+		}
+	}
+}
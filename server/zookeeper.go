@@ -0,0 +1,147 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/health-checker/options"
+)
+
+// Check that a ZooKeeper server answers ruok with imok, and optionally that mntr reports the expected state
+type zookeeperCheck struct {
+	zookeeper options.ZookeeperCheck
+	opts      *options.Options
+}
+
+func (c *zookeeperCheck) Name() string {
+	return fmt.Sprintf("ZooKeeper check of %s", c.zookeeper.Address)
+}
+
+func (c *zookeeperCheck) Run() *checkResult {
+	return &checkResult{Err: attemptZookeeperCheck(c.zookeeper, c.opts)}
+}
+
+func attemptZookeeperCheck(zookeeper options.ZookeeperCheck, opts *options.Options) error {
+	logger := opts.Logger
+	logger.Infof("Attempting to send ruok to ZooKeeper at %s...", zookeeper.Address)
+
+	reply, err := sendZookeeperCommand(zookeeper.Address, "ruok")
+	if err != nil {
+		return err
+	}
+	if reply != "imok" {
+		return UnexpectedZookeeperReply{command: "ruok", expected: "imok", exact: true, actual: reply}
+	}
+
+	if len(zookeeper.States) == 0 && zookeeper.MinSyncedFollowers <= 0 {
+		return nil
+	}
+
+	logger.Infof("Attempting to send mntr to ZooKeeper at %s...", zookeeper.Address)
+
+	reply, err = sendZookeeperCommand(zookeeper.Address, "mntr")
+	if err != nil {
+		return err
+	}
+	stats := parseZookeeperStats(reply)
+
+	state, hasState := stats["zk_server_state"]
+	if !hasState {
+		return UnexpectedZookeeperReply{command: "mntr", expected: "zk_server_state", actual: reply}
+	}
+	if len(zookeeper.States) > 0 && !containsString(zookeeper.States, state) {
+		return UnexpectedZookeeperState{expected: zookeeper.States, actual: state}
+	}
+
+	// Only the leader reports how many followers are in sync
+	if zookeeper.MinSyncedFollowers > 0 && state == "leader" {
+		syncedFollowers, err := strconv.Atoi(stats["zk_synced_followers"])
+		if err != nil {
+			return UnexpectedZookeeperReply{command: "mntr", expected: "zk_synced_followers", actual: reply}
+		}
+		if syncedFollowers < zookeeper.MinSyncedFollowers {
+			return TooFewSyncedFollowers{expected: zookeeper.MinSyncedFollowers, actual: syncedFollowers}
+		}
+	}
+
+	return nil
+}
+
+// Send a four letter word to ZooKeeper, which replies and then closes the connection
+func sendZookeeperCommand(address string, command string) (string, error) {
+	conn, err := net.DialTimeout("tcp", address, defaultCheckTimeout)
+	if err != nil {
+		return "", err
+	}
+
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(defaultCheckTimeout))
+
+	if _, err := conn.Write([]byte(command)); err != nil {
+		return "", err
+	}
+
+	reply, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes.TrimSpace(reply)), nil
+}
+
+// Parse the tab separated key/value lines returned by mntr
+func parseZookeeperStats(reply string) map[string]string {
+	stats := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(reply))
+	for scanner.Scan() {
+		keyAndValue := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(keyAndValue) == 2 {
+			stats[strings.TrimSpace(keyAndValue[0])] = strings.TrimSpace(keyAndValue[1])
+		}
+	}
+	return stats
+}
+
+// Custom error types
+
+// If exact is true, the whole reply must equal expected, otherwise it only has to contain it
+type UnexpectedZookeeperReply struct {
+	command  string
+	expected string
+	exact    bool
+	actual   string
+}
+
+func (err UnexpectedZookeeperReply) Error() string {
+	relation := "contain"
+	if err.exact {
+		relation = "be"
+	}
+	// Since ZooKeeper 3.5, four letter words must be whitelisted, and those that aren't get a reply explaining that
+	return fmt.Sprintf("expected the reply to %s to %s %s but got %q", err.command, relation, err.expected, err.actual)
+}
+
+type UnexpectedZookeeperState struct {
+	expected []string
+	actual   string
+}
+
+func (err UnexpectedZookeeperState) Error() string {
+	return fmt.Sprintf("zk_server_state is %s, expected one of %v", err.actual, err.expected)
+}
+
+type TooFewSyncedFollowers struct {
+	expected int
+	actual   int
+}
+
+func (err TooFewSyncedFollowers) Error() string {
+	return fmt.Sprintf("zk_synced_followers is %d, expected at least %d", err.actual, err.expected)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net"
+	"testing"
+
+	"github.com/gruntwork-io/health-checker/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptZookeeperCheck(t *testing.T) {
+	t.Parallel()
+
+	leader := startFakeZookeeper(t, "imok", "zk_version\t3.6.2\nzk_server_state\tleader\nzk_synced_followers\t2\n")
+	follower := startFakeZookeeper(t, "imok", "zk_version\t3.6.2\nzk_server_state\tfollower\n")
+	notWhitelisted := startFakeZookeeper(t, "ruok is not executed because it is not in the whitelist.", "")
+
+	testCases := []struct {
+		name        string
+		zookeeper   options.ZookeeperCheck
+		expectedErr string
+	}{
+		{"ruok", options.ZookeeperCheck{Address: follower}, ""},
+		{"ruok not whitelisted", options.ZookeeperCheck{Address: notWhitelisted}, "expected the reply to ruok to be imok"},
+		{"expected state", options.ZookeeperCheck{Address: follower, States: []string{"leader", "follower"}}, ""},
+		{"unexpected state", options.ZookeeperCheck{Address: follower, States: []string{"leader"}}, "zk_server_state is follower"},
+		{"enough synced followers", options.ZookeeperCheck{Address: leader, MinSyncedFollowers: 2}, ""},
+		{"too few synced followers", options.ZookeeperCheck{Address: leader, MinSyncedFollowers: 3}, "zk_synced_followers is 2, expected at least 3"},
+		{"synced followers ignored on followers", options.ZookeeperCheck{Address: follower, MinSyncedFollowers: 3}, ""},
+	}
+
+	opts := createOptionsForTest(t, 5, []string{}, "", []int{})
+
+	for _, testCase := range testCases {
+		err := attemptZookeeperCheck(testCase.zookeeper, opts)
+		if testCase.expectedErr == "" {
+			assert.Nil(t, err, testCase.name)
+		} else if assert.NotNil(t, err, testCase.name) {
+			assert.Contains(t, err.Error(), testCase.expectedErr, testCase.name)
+		}
+	}
+}
+
+// Start a fake ZooKeeper that answers ruok and mntr with the given replies and then closes the connection, just like
+// the real thing. Returns the address of the server.
+func startFakeZookeeper(t *testing.T, ruokReply string, mntrReply string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		assert.FailNow(t, "Failed to start fake ZooKeeper: %s", err.Error())
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			command := make([]byte, 4)
+			if _, err := conn.Read(command); err == nil {
+				switch string(command) {
+				case "ruok":
+					conn.Write([]byte(ruokReply))
+				case "mntr":
+					conn.Write([]byte(mntrReply))
+				}
+			}
+			conn.Close()
+		}
+	}()
+
+	return l.Addr().String()
+}